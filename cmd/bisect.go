@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var bisectCmd = &cobra.Command{
+	Use:   "bisect <failing-test>",
+	Short: "Find the test files that make a test fail when run before it",
+	Long: `Replays the file order of the worker that last failed the test, as recorded
+in the run history, then bisects the files that ran before it to find the
+smallest set that still makes the test fail.
+
+The test is given as Class::method, e.g. 'Tests\Unit\UserTest::testCreate'.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+		cfg, baseDir, err := loadPHPUnitConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		r := runner.New(cfg, runnerConfig, baseDir, nil)
+		result, err := r.Bisect(args[0], out)
+		if errors.Is(err, runner.ErrNotReproduced) {
+			return fmt.Errorf("%s passed when replaying the recorded file order, it may be flaky rather than order dependent", args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(out)
+		switch len(result.Polluters) {
+		case 0:
+			_, _ = fmt.Fprintf(out, "%s fails on its own, it does not depend on the files run before it.\n", result.Test)
+		case 1:
+			_, _ = fmt.Fprintf(out, "Polluting test file: %s\n", result.Polluters[0])
+		default:
+			_, _ = fmt.Fprintf(out, "%s fails only when these %d files run before it:\n", result.Test, len(result.Polluters))
+			for _, file := range result.Polluters {
+				_, _ = fmt.Fprintf(out, "  %s\n", file)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bisectCmd)
+}
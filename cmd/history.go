@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRuns int
+	historyTop  int
+)
+
+var historyCmd = &cobra.Command{
+	Use:          "history",
+	Short:        "Show trends from previous runs",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+
+		runs, err := history.NewStore(runnerConfig.ConfigBuildDir, 0).Load()
+		if err != nil {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+		if historyRuns > 0 && len(runs) > historyRuns {
+			runs = runs[len(runs)-historyRuns:]
+		}
+
+		out := cmd.OutOrStdout()
+		if len(runs) == 0 {
+			_, _ = fmt.Fprintln(out, "No runs recorded yet.")
+			return nil
+		}
+
+		printPassRates(out, runs)
+		printSlowestGrowing(out, history.SlowestGrowing(runs, historyTop))
+		printFlaky(out, history.Flaky(runs, historyTop))
+		printSuiteTimes(out, history.SuiteTimes(runs))
+		return nil
+	},
+}
+
+func printPassRates(out io.Writer, runs []history.Run) {
+	_, _ = fmt.Fprintln(out, "Pass rate")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Started\tCommit\tSeed\tTests\tFailed\tPass rate\tWall time")
+	for _, run := range runs {
+		commit := run.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		if commit == "" {
+			commit = "-"
+		}
+
+		rate := 100.0
+		if len(run.Tests) > 0 {
+			rate = float64(len(run.Tests)-run.Failed()) / float64(len(run.Tests)) * 100
+		}
+
+		seed := "-"
+		if run.Seed != 0 {
+			seed = fmt.Sprintf("%d", run.Seed)
+		}
+
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\t%d\t%.1f%%\t%s\n",
+			run.StartedAt.Local().Format("2006-01-02 15:04"),
+			commit,
+			seed,
+			len(run.Tests),
+			run.Failed(),
+			rate,
+			formatDuration(run.Duration()),
+		)
+	}
+	_ = tw.Flush()
+}
+
+func printSlowestGrowing(out io.Writer, trends []history.TestTrend) {
+	_, _ = fmt.Fprintln(out, "\nSlowest growing tests")
+	if len(trends) == 0 {
+		_, _ = fmt.Fprintln(out, "  None")
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Test\tBefore\tAfter\tGrowth")
+	for _, t := range trends {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t+%s\n", t.Name, formatDuration(t.Before), formatDuration(t.After), formatDuration(t.Growth()))
+	}
+	_ = tw.Flush()
+}
+
+func printFlaky(out io.Writer, flaky []history.FlakyTest) {
+	_, _ = fmt.Fprintln(out, "\nFlaky tests")
+	if len(flaky) == 0 {
+		_, _ = fmt.Fprintln(out, "  None")
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Test\tFailures\tFlips\tSame commit")
+	for _, t := range flaky {
+		sameCommit := "no"
+		if t.SameCommit {
+			sameCommit = "yes"
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%d/%d\t%d\t%s\n", t.Name, t.Failures, t.Runs, t.Flips, sameCommit)
+	}
+	_ = tw.Flush()
+}
+
+func printSuiteTimes(out io.Writer, suites []history.SuiteTime) {
+	_, _ = fmt.Fprintln(out, "\nAverage wall time per suite")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Suite\tRuns\tAverage")
+	for _, s := range suites {
+		name := s.Suite
+		if name == "" {
+			name = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%s\n", name, s.Runs, formatDuration(s.Average))
+	}
+	_ = tw.Flush()
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyRuns, "runs", 0, "Only consider the most recent number of runs (0 for all)")
+	historyCmd.Flags().IntVar(&historyTop, "top", 10, "Number of tests to list per section")
+	rootCmd.AddCommand(historyCmd)
+}
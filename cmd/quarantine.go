@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/config"
+	"github.com/alexdempster44/phpunit-parallel/internal/history"
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var quarantineStableRuns int
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "Maintain the list of quarantined tests",
+	Long: `Quarantined tests still run, but their failures do not fail the build.
+The list is kept in the <quarantine> element of the runner configuration file.`,
+}
+
+var quarantineListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List quarantined tests",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+		for _, test := range runnerConfig.Quarantine {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), test)
+		}
+		return nil
+	},
+}
+
+var quarantineAddCmd = &cobra.Command{
+	Use:          "add <test>...",
+	Short:        "Quarantine tests (Class::method or a whole class)",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+		tests := runnerConfig.Quarantine
+		for _, test := range args {
+			if !slices.Contains(tests, test) {
+				tests = append(tests, test)
+			}
+		}
+		return writeQuarantine(cmd, tests)
+	},
+}
+
+var quarantineRemoveCmd = &cobra.Command{
+	Use:          "remove <test>...",
+	Short:        "Remove tests from quarantine",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+		tests := slices.DeleteFunc(slices.Clone(runnerConfig.Quarantine), func(test string) bool {
+			return slices.Contains(args, test)
+		})
+		return writeQuarantine(cmd, tests)
+	},
+}
+
+var quarantineSyncCmd = &cobra.Command{
+	Use:          "sync",
+	Short:        "Quarantine flaky tests from the run history and release stable ones",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
+		}
+
+		runs, err := history.NewStore(runnerConfig.ConfigBuildDir, 0).Load()
+		if err != nil {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+
+		var tests, released []string
+		for _, test := range runnerConfig.Quarantine {
+			if stable(runs, test, quarantineStableRuns) {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Released %s\n", test)
+				released = append(released, test)
+				continue
+			}
+			tests = append(tests, test)
+		}
+
+		// Released tests are still flaky by their older runs, so they are not
+		// quarantined again
+		quarantined := output.NewTestSet(append(slices.Clone(tests), released...))
+		for _, test := range history.FlakyNames(runs) {
+			if !quarantined.Has(test) {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Quarantined %s\n", test)
+				tests = append(tests, test)
+			}
+		}
+
+		return writeQuarantine(cmd, tests)
+	},
+}
+
+// stable reports whether every test matching name passed in each of the last
+// n runs it took part in.
+func stable(runs []history.Run, name string, n int) bool {
+	match := output.NewTestSet([]string{name})
+	seen := 0
+	for i := len(runs) - 1; i >= 0 && seen < n; i-- {
+		ran := false
+		for _, t := range runs[i].Tests {
+			if !match.Has(t.Name) {
+				continue
+			}
+			if t.Status == history.StatusFailed {
+				return false
+			}
+			ran = true
+		}
+		if ran {
+			seen++
+		}
+	}
+	return seen >= n
+}
+
+func writeQuarantine(cmd *cobra.Command, tests []string) error {
+	path := runnerConfigFile
+	if path == "" {
+		path = defaultRunnerConfigFile
+	}
+	if err := config.WriteQuarantine(path, tests); err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d test(s) quarantined in %s\n", len(tests), path)
+	return nil
+}
+
+func init() {
+	quarantineSyncCmd.Flags().IntVar(&quarantineStableRuns, "stable-runs", 10, "Release tests that passed in each of their last number of runs")
+	quarantineCmd.AddCommand(quarantineListCmd, quarantineAddCmd, quarantineRemoveCmd, quarantineSyncCmd)
+	rootCmd.AddCommand(quarantineCmd)
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
+	"strings"
 
 	"github.com/alexdempster44/phpunit-parallel/internal/config"
 	"github.com/alexdempster44/phpunit-parallel/internal/output"
@@ -23,38 +24,62 @@ var (
 )
 
 var rootCmd = &cobra.Command{
-	Use:          "phpunit-parallel",
+	Use:          "phpunit-parallel [flags] [-- phpunit-args...]",
 	Short:        "Run PHPUnit tests in parallel",
 	SilenceUsage: true,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		configToLoad := runnerConfigFile
-		if configToLoad == "" {
-			if _, err := os.Stat(defaultRunnerConfigFile); err == nil {
-				configToLoad = defaultRunnerConfigFile
-			}
+	// Used for the suggestions given for stray arguments
+	SuggestionsMinimumDistance: 2,
+	Args: func(cmd *cobra.Command, args []string) error {
+		// Only arguments after -- are forwarded to PHPUnit, anything before it
+		// is most likely a mistyped command
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			args = args[:dash]
 		}
-
-		if configToLoad != "" {
-			cfg, err := config.ParseRunner(configToLoad)
-			if err != nil {
-				return fmt.Errorf("failed to parse runner config: %w", err)
-			}
-			runnerConfig = cfg
+		if len(args) == 0 {
+			return nil
+		}
+		msg := fmt.Sprintf("unknown command %q for %q", args[0], cmd.CommandPath())
+		if suggestions := cmd.SuggestionsFor(args[0]); len(suggestions) > 0 {
+			msg += "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+		}
+		return errors.New(msg + "\n\nPass PHPUnit arguments after --")
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadRunnerConfig(cmd); err != nil {
+			return err
 		}
 
 		if v, ok := os.LookupEnv("PHPUNIT_PARALLEL_WORKERS"); ok {
-			w, err := strconv.Atoi(v)
-			if err != nil {
-				return fmt.Errorf("invalid PHPUNIT_PARALLEL_WORKERS value: %w", err)
-			}
-			runnerConfig.Workers = w
+			runnerConfig.Workers = v
 		}
 
 		if cmd.Flags().Changed("workers") {
-			runnerConfig.Workers, _ = cmd.Flags().GetInt("workers")
+			runnerConfig.Workers, _ = cmd.Flags().GetString("workers")
+		}
+		if cmd.Flags().Changed("worker-memory") {
+			runnerConfig.WorkerMemory, _ = cmd.Flags().GetString("worker-memory")
+		}
+		if _, err := runnerConfig.ResolveWorkers(0); err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("recycle-after") {
+			runnerConfig.MaxFilesPerProcess, _ = cmd.Flags().GetInt("recycle-after")
+		}
+		if cmd.Flags().Changed("timeline") {
+			runnerConfig.Timeline, _ = cmd.Flags().GetString("timeline")
+		}
+		if cmd.Flags().Changed("theme") {
+			name, _ := cmd.Flags().GetString("theme")
+			runnerConfig.Theme = config.Theme{Name: name}
+		}
+		if cmd.Flags().Changed("icons") {
+			runnerConfig.Icons, _ = cmd.Flags().GetString("icons")
+		}
+		if cmd.Flags().Changed("editor") {
+			runnerConfig.Editor, _ = cmd.Flags().GetString("editor")
 		}
-		if cmd.Flags().Changed("config-build-dir") {
-			runnerConfig.ConfigBuildDir, _ = cmd.Flags().GetString("config-build-dir")
+		if cmd.Flags().Changed("history-size") {
+			runnerConfig.HistorySize, _ = cmd.Flags().GetInt("history-size")
 		}
 		if cmd.Flags().Changed("before") {
 			runnerConfig.Before, _ = cmd.Flags().GetString("before")
@@ -64,6 +89,7 @@ var rootCmd = &cobra.Command{
 		}
 		if cmd.Flags().Changed("run-worker") {
 			runnerConfig.RunWorker, _ = cmd.Flags().GetString("run-worker")
+			runnerConfig.RunWorkerArgs = nil
 		}
 		if cmd.Flags().Changed("after-worker") {
 			runnerConfig.AfterWorker, _ = cmd.Flags().GetString("after-worker")
@@ -71,6 +97,18 @@ var rootCmd = &cobra.Command{
 		if cmd.Flags().Changed("after") {
 			runnerConfig.After, _ = cmd.Flags().GetString("after")
 		}
+		if cmd.Flags().Changed("hook-timeout") {
+			runnerConfig.HookTimeout, _ = cmd.Flags().GetString("hook-timeout")
+		}
+		if cmd.Flags().Changed("shutdown-grace") {
+			runnerConfig.ShutdownGrace, _ = cmd.Flags().GetString("shutdown-grace")
+		}
+		if _, err := runnerConfig.HookTimeoutDuration(); err != nil {
+			return err
+		}
+		if _, err := runnerConfig.ShutdownGraceDuration(); err != nil {
+			return err
+		}
 		if cmd.Flags().Changed("filter") {
 			runnerConfig.Filter, _ = cmd.Flags().GetString("filter")
 		}
@@ -83,35 +121,65 @@ var rootCmd = &cobra.Command{
 		if cmd.Flags().Changed("exclude-group") {
 			runnerConfig.ExcludeGroup, _ = cmd.Flags().GetString("exclude-group")
 		}
+		if cmd.Flags().Changed("static-filter") {
+			runnerConfig.StaticFilter, _ = cmd.Flags().GetBool("static-filter")
+		}
+		if cmd.Flags().Changed("random-order") {
+			runnerConfig.RandomOrder, _ = cmd.Flags().GetBool("random-order")
+		}
+		if cmd.Flags().Changed("seed") {
+			runnerConfig.Seed, _ = cmd.Flags().GetInt64("seed")
+			runnerConfig.RandomOrder = true
+		}
 
-		return nil
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if !cmd.Flags().Changed("configuration") {
-			if runnerConfig.Configuration != "" {
-				configFile = runnerConfig.Configuration
-			} else if _, err := os.Stat("phpunit.xml"); err != nil {
-				if _, err := os.Stat("phpunit.xml.dist"); err == nil {
-					configFile = "phpunit.xml.dist"
-				}
-			}
+		if cmd.Flags().Changed("coverage-clover") {
+			runnerConfig.Coverage.Clover, _ = cmd.Flags().GetString("coverage-clover")
+		}
+		if cmd.Flags().Changed("coverage-html") {
+			runnerConfig.Coverage.HTML, _ = cmd.Flags().GetString("coverage-html")
+		}
+		if cmd.Flags().Changed("coverage-cobertura") {
+			runnerConfig.Coverage.Cobertura, _ = cmd.Flags().GetString("coverage-cobertura")
+		}
+		if cmd.Flags().Changed("coverage-text") {
+			runnerConfig.Coverage.Text, _ = cmd.Flags().GetString("coverage-text")
+		}
+		if cmd.Flags().Changed("coverage-driver") {
+			runnerConfig.Coverage.Driver, _ = cmd.Flags().GetString("coverage-driver")
+		}
+		if err := runnerConfig.Coverage.Validate(); err != nil {
+			return err
 		}
 
-		cfg, err := config.ParsePHPUnit(configFile)
-		if err != nil {
-			return fmt.Errorf("failed to parse config: %w", err)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			runnerConfig.PHPUnitArgs = append(runnerConfig.PHPUnitArgs, args[dash:]...)
+		}
+
+		if err := runnerConfig.ValidatePHPUnitArgs(); err != nil {
+			return err
 		}
 
-		baseDir := filepath.Dir(configFile)
-		if baseDir == "." {
-			baseDir, _ = os.Getwd()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, baseDir, err := loadPHPUnitConfig(cmd)
+		if err != nil {
+			return err
 		}
 
 		var out output.Output
 		if teamcity {
 			out = output.NewTeamCityOutput()
 		} else {
-			out = tui.New()
+			styles, err := tui.ThemeStyles(runnerConfig.Theme, runnerConfig.Icons)
+			if err != nil {
+				return err
+			}
+			keys, err := tui.KeyMapFor(runnerConfig.Keys)
+			if err != nil {
+				return err
+			}
+			out = tui.New(styles, keys)
 		}
 
 		r := runner.New(cfg, runnerConfig, baseDir, out)
@@ -119,22 +187,101 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// loadRunnerConfig reads the runner configuration file, if any, and applies
+// the flags shared with subcommands.
+func loadRunnerConfig(cmd *cobra.Command) error {
+	configToLoad := runnerConfigFile
+	if configToLoad == "" {
+		if _, err := os.Stat(defaultRunnerConfigFile); err == nil {
+			configToLoad = defaultRunnerConfigFile
+		}
+	}
+
+	if configToLoad != "" {
+		cfg, err := config.ParseRunner(configToLoad)
+		if err != nil {
+			return fmt.Errorf("failed to parse runner config: %w", err)
+		}
+		runnerConfig = cfg
+	}
+
+	if path := config.UserConfigPath(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			user, err := config.ParseUser(path)
+			if err != nil {
+				return fmt.Errorf("failed to parse user config: %w", err)
+			}
+			runnerConfig.ApplyUser(user)
+		}
+	}
+
+	if cmd.Flags().Changed("config-build-dir") {
+		runnerConfig.ConfigBuildDir, _ = cmd.Flags().GetString("config-build-dir")
+	}
+
+	return nil
+}
+
+// loadPHPUnitConfig parses the PHPUnit configuration and returns it along with
+// the project directory it belongs to.
+func loadPHPUnitConfig(cmd *cobra.Command) (*config.PHPUnit, string, error) {
+	if !cmd.Flags().Changed("configuration") {
+		if runnerConfig.Configuration != "" {
+			configFile = runnerConfig.Configuration
+		} else if _, err := os.Stat("phpunit.xml"); err != nil {
+			if _, err := os.Stat("phpunit.xml.dist"); err == nil {
+				configFile = "phpunit.xml.dist"
+			}
+		}
+	}
+
+	cfg, err := config.ParsePHPUnit(configFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	baseDir := filepath.Dir(configFile)
+	if baseDir == "." {
+		baseDir, _ = os.Getwd()
+	}
+
+	return cfg, baseDir, nil
+}
+
 func init() {
-	rootCmd.Flags().StringVarP(&configFile, "configuration", "c", "phpunit.xml", "PHPUnit configuration file")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "configuration", "c", "phpunit.xml", "PHPUnit configuration file")
 	rootCmd.Flags().BoolVar(&teamcity, "teamcity", false, "Output in TeamCity format")
 
-	rootCmd.Flags().StringVar(&runnerConfigFile, "runner-config", "", "Runner configuration file")
-	rootCmd.Flags().IntVarP(&runnerConfig.Workers, "workers", "w", runnerConfig.Workers, "Number of parallel workers")
-	rootCmd.Flags().StringVar(&runnerConfig.ConfigBuildDir, "config-build-dir", runnerConfig.ConfigBuildDir, "Directory for generated config files")
+	rootCmd.PersistentFlags().StringVar(&runnerConfigFile, "runner-config", "", "Runner configuration file")
+	rootCmd.Flags().StringVarP(&runnerConfig.Workers, "workers", "w", runnerConfig.Workers, "Number of parallel workers, a percentage of CPUs (e.g. 50%), or auto")
+	rootCmd.Flags().StringVar(&runnerConfig.WorkerMemory, "worker-memory", "", "Estimated memory per worker used by --workers auto (learned from previous runs if unset)")
+	rootCmd.Flags().IntVar(&runnerConfig.MaxFilesPerProcess, "recycle-after", runnerConfig.MaxFilesPerProcess, "Start a fresh PHPUnit process after this many test files (0 runs each worker's files in one process)")
+	rootCmd.PersistentFlags().StringVar(&runnerConfig.ConfigBuildDir, "config-build-dir", runnerConfig.ConfigBuildDir, "Directory for generated config files")
+	rootCmd.Flags().IntVar(&runnerConfig.HistorySize, "history-size", runnerConfig.HistorySize, "Number of runs to keep in the run history (0 disables it)")
+	rootCmd.Flags().StringVar(&runnerConfig.Timeline, "timeline", "", "Write a timeline of each worker's hooks and test files to an SVG or HTML file")
+	rootCmd.Flags().String("theme", "", "Terminal UI theme: dark, light, high-contrast or no-color (default dark, or no-color when NO_COLOR is set)")
+	rootCmd.Flags().StringVar(&runnerConfig.Icons, "icons", "", "Terminal UI icon set: unicode or ascii")
+	rootCmd.Flags().StringVar(&runnerConfig.Editor, "editor", "", "Command opening a file from the terminal UI, with {file} and {line} placeholders (default $VISUAL or $EDITOR)")
 	rootCmd.Flags().StringVar(&runnerConfig.Before, "before", "", "Command to run once before all workers start")
 	rootCmd.Flags().StringVar(&runnerConfig.BeforeWorker, "before-worker", "", "Command to run before each worker starts")
 	rootCmd.Flags().StringVar(&runnerConfig.RunWorker, "run-worker", runnerConfig.RunWorker, "Command to run PHPUnit for each worker")
 	rootCmd.Flags().StringVar(&runnerConfig.AfterWorker, "after-worker", "", "Command to run after each worker completes")
 	rootCmd.Flags().StringVar(&runnerConfig.After, "after", "", "Command to run once after all workers complete")
+	rootCmd.Flags().StringVar(&runnerConfig.HookTimeout, "hook-timeout", "", "Maximum duration of each before-worker/after-worker hook (e.g. 5m)")
+	rootCmd.Flags().StringVar(&runnerConfig.ShutdownGrace, "shutdown-grace", runnerConfig.ShutdownGrace, "Time workers are given to exit after SIGTERM before being killed")
+	rootCmd.Flags().StringVar(&runnerConfig.Coverage.Clover, "coverage-clover", "", "Write merged code coverage in Clover XML format to file")
+	rootCmd.Flags().StringVar(&runnerConfig.Coverage.HTML, "coverage-html", "", "Write merged code coverage in HTML format to directory")
+	rootCmd.Flags().StringVar(&runnerConfig.Coverage.Cobertura, "coverage-cobertura", "", "Write merged code coverage in Cobertura XML format to file")
+	rootCmd.Flags().StringVar(&runnerConfig.Coverage.Text, "coverage-text", "", "Write merged code coverage in text format to file (default stdout)")
+	rootCmd.Flags().Lookup("coverage-text").NoOptDefVal = "php://stdout"
+	rootCmd.Flags().StringVar(&runnerConfig.Coverage.Driver, "coverage-driver", "", "Code coverage driver to enable in workers (xdebug or pcov)")
 	rootCmd.Flags().StringVar(&runnerConfig.Filter, "filter", "", "Filter which tests to run (passed to PHPUnit --filter)")
 	rootCmd.Flags().StringVar(&runnerConfig.TestSuffix, "test-suffix", runnerConfig.TestSuffix, "Suffix for test files")
 	rootCmd.Flags().StringVar(&runnerConfig.Group, "group", "", "Only run tests from the specified group(s)")
 	rootCmd.Flags().StringVar(&runnerConfig.ExcludeGroup, "exclude-group", "", "Exclude tests from the specified group(s)")
+	rootCmd.Flags().BoolVar(&runnerConfig.RandomOrder, "random-order", false, "Shuffle test files before distributing them to workers")
+	rootCmd.Flags().Int64Var(&runnerConfig.Seed, "seed", 0, "Seed for --random-order, to reproduce a previous order (implies --random-order)")
+	rootCmd.Flags().BoolVar(&runnerConfig.StaticFilter, "static-filter", runnerConfig.StaticFilter, "Skip test files that cannot match --filter, --group or --exclude-group")
 }
 
 func SetVersionInfo(version string) {
@@ -143,6 +290,9 @@ func SetVersionInfo(version string) {
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if errors.Is(err, runner.ErrCancelled) {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var quarantinePattern = regexp.MustCompile(`(?s)[ \t]*<quarantine\s*/>\n?|[ \t]*<quarantine>.*?</quarantine>\n?`)
+
+// WriteQuarantine replaces the <quarantine> list of the runner config file at
+// path, leaving the rest of the file untouched. The file is created if it does
+// not exist.
+func WriteQuarantine(path string, tests []string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data = []byte("<runner>\n</runner>\n")
+	} else if err != nil {
+		return err
+	}
+
+	var block strings.Builder
+	if len(tests) > 0 {
+		block.WriteString("    <quarantine>\n")
+		for _, test := range tests {
+			var escaped bytes.Buffer
+			_ = xml.EscapeText(&escaped, []byte(test))
+			block.WriteString("        <test>" + escaped.String() + "</test>\n")
+		}
+		block.WriteString("    </quarantine>\n")
+	}
+
+	content := string(data)
+	if loc := quarantinePattern.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]] + block.String() + content[loc[1]:]
+	} else if end := strings.LastIndex(content, "</runner>"); end >= 0 {
+		content = content[:end] + block.String() + content[end:]
+	} else {
+		content += "<runner>\n" + block.String() + "</runner>\n"
+	}
+
+	return os.WriteFile(path, []byte(content), 0644)
+}
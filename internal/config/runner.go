@@ -2,32 +2,140 @@ package config
 
 import (
 	"encoding/xml"
+	"fmt"
 	"os"
-	"runtime"
+	"strings"
+	"time"
 )
 
+// reservedPHPUnitArgs are managed by the runner for each worker and cannot be
+// passed through to PHPUnit.
+var reservedPHPUnitArgs = map[string]string{
+	"-c":                        "the runner generates a configuration per worker",
+	"--configuration":           "the runner generates a configuration per worker",
+	"--no-configuration":        "the runner generates a configuration per worker",
+	"--teamcity":                "the runner always enables TeamCity output",
+	"--log-teamcity":            "the runner reads TeamCity output from each worker",
+	"--log-junit":               "each worker would overwrite the same report",
+	"--log-events-text":         "each worker would overwrite the same report",
+	"--log-events-verbose-text": "each worker would overwrite the same report",
+	"--testdox-html":            "each worker would overwrite the same report",
+	"--testdox-text":            "each worker would overwrite the same report",
+	"--testdox":                 "it replaces the TeamCity output the runner reads",
+	"--debug":                   "it replaces the TeamCity output the runner reads",
+	"--no-output":               "it hides the TeamCity output the runner reads",
+	"--filter":                  "use the runner's --filter option",
+	"--group":                   "use the runner's --group option",
+	"--exclude-group":           "use the runner's --exclude-group option",
+	"--test-suffix":             "use the runner's --test-suffix option",
+	"--coverage-php":            "use the runner's coverage options",
+	"--coverage-clover":         "use the runner's --coverage-clover option",
+	"--coverage-html":           "use the runner's --coverage-html option",
+	"--coverage-cobertura":      "use the runner's --coverage-cobertura option",
+	"--coverage-text":           "use the runner's --coverage-text option",
+	"--coverage-xml":            "each worker would only report the coverage of its own files",
+	"--coverage-crap4j":         "each worker would only report the coverage of its own files",
+	"--list-tests":              "it prevents tests from running",
+	"--list-tests-xml":          "it prevents tests from running",
+	"--list-groups":             "it prevents tests from running",
+	"--list-suites":             "it prevents tests from running",
+}
+
+// phpunitValueArgs are the PHPUnit options whose value may be given as the
+// next argument rather than after an equals sign.
+var phpunitValueArgs = map[string]bool{
+	"-d":                       true,
+	"--bootstrap":              true,
+	"--include-path":           true,
+	"--cache-directory":        true,
+	"--generate-baseline":      true,
+	"--use-baseline":           true,
+	"--testsuite":              true,
+	"--exclude-testsuite":      true,
+	"--exclude-filter":         true,
+	"--covers":                 true,
+	"--uses":                   true,
+	"--requires-php-extension": true,
+	"--default-time-limit":     true,
+	"--columns":                true,
+	"--order-by":               true,
+	"--random-order-seed":      true,
+	"--extension":              true,
+	"--coverage-filter":        true,
+}
+
 type Runner struct {
-	XMLName        xml.Name `xml:"runner"`
-	Workers        int      `xml:"workers"`
-	Configuration  string   `xml:"configuration"`
-	ConfigBuildDir string   `xml:"config-build-dir"`
-	TestSuffix     string   `xml:"test-suffix"`
-	Before         string   `xml:"before"`
-	BeforeWorker   string   `xml:"before-worker"`
-	RunWorker      string   `xml:"run-worker"`
-	AfterWorker    string   `xml:"after-worker"`
-	After          string   `xml:"after"`
-	Filter         string   `xml:"-"` // CLI-only, not in XML config
-	Group          string   `xml:"-"` // CLI-only, not in XML config
-	ExcludeGroup   string   `xml:"-"` // CLI-only, not in XML config
+	XMLName            xml.Name `xml:"runner"`
+	Workers            string   `xml:"workers"`
+	WorkerMemory       string   `xml:"worker-memory"`
+	Configuration      string   `xml:"configuration"`
+	ConfigBuildDir     string   `xml:"config-build-dir"`
+	TestSuffix         string   `xml:"test-suffix"`
+	MaxFilesPerProcess int      `xml:"max-files-per-process"`
+	HistorySize        int      `xml:"history-size"`
+	Before             string   `xml:"before"`
+	BeforeWorker       string   `xml:"before-worker"`
+	RunWorker          string   `xml:"run-worker"`
+	RunWorkerArgs      []string `xml:"-"` // Parsed from <arg> elements of <run-worker>
+	AfterWorker        string   `xml:"after-worker"`
+	After              string   `xml:"after"`
+	HookTimeout        string   `xml:"hook-timeout"`
+	ShutdownGrace      string   `xml:"shutdown-grace"`
+	StaticFilter       bool     `xml:"static-filter"`
+	RandomOrder        bool     `xml:"random-order"`
+	Seed               int64    `xml:"seed"`
+	PHPUnitArgs        []string `xml:"phpunit-args>arg"`
+	Env                []EnvVar `xml:"env"`
+	Coverage           Coverage `xml:"coverage"`
+	Quarantine         []string `xml:"quarantine>test"`
+	Editor             string   `xml:"editor"`
+	Timeline           string   `xml:"timeline"`
+	Theme              Theme    `xml:"theme"`
+	Icons              string   `xml:"icons"`
+	Keys               Keys     `xml:"-"` // User config only
+	Filter             string   `xml:"-"` // CLI-only, not in XML config
+	Group              string   `xml:"-"` // CLI-only, not in XML config
+	ExcludeGroup       string   `xml:"-"` // CLI-only, not in XML config
+}
+
+type EnvVar struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type Coverage struct {
+	Clover    string `xml:"clover"`
+	HTML      string `xml:"html"`
+	Cobertura string `xml:"cobertura"`
+	Text      string `xml:"text"`
+	Driver    string `xml:"driver"`
+	PHPCov    string `xml:"phpcov"`
+}
+
+func (c Coverage) Enabled() bool {
+	return c.Clover != "" || c.HTML != "" || c.Cobertura != "" || c.Text != ""
+}
+
+func (c Coverage) Validate() error {
+	switch c.Driver {
+	case "", "xdebug", "pcov":
+		return nil
+	}
+	return fmt.Errorf("invalid coverage driver %q, expected xdebug or pcov", c.Driver)
 }
 
 func DefaultRunner() *Runner {
 	return &Runner{
-		Workers:        max(runtime.NumCPU()-2, 1),
+		Workers:        autoWorkers,
 		ConfigBuildDir: ".phpunit-parallel",
 		RunWorker:      "vendor/bin/phpunit",
 		TestSuffix:     "Test.php",
+		StaticFilter:   true,
+		ShutdownGrace:  "10s",
+		HistorySize:    50,
+		Coverage: Coverage{
+			PHPCov: "vendor/bin/phpcov",
+		},
 	}
 }
 
@@ -42,5 +150,53 @@ func ParseRunner(path string) (*Runner, error) {
 		return nil, err
 	}
 
+	var runWorker struct {
+		Args []string `xml:"run-worker>arg"`
+	}
+	if err := xml.Unmarshal(data, &runWorker); err != nil {
+		return nil, err
+	}
+	if len(runWorker.Args) > 0 {
+		cfg.RunWorker = ""
+		cfg.RunWorkerArgs = runWorker.Args
+	}
+
 	return cfg, nil
 }
+
+func (r *Runner) ValidatePHPUnitArgs() error {
+	for i := 0; i < len(r.PHPUnitArgs); i++ {
+		arg := r.PHPUnitArgs[i]
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("PHPUnit argument %s is not supported: test files are distributed from the configured test suites", arg)
+		}
+
+		name, _, hasValue := strings.Cut(arg, "=")
+		if reason, ok := reservedPHPUnitArgs[name]; ok {
+			return fmt.Errorf("PHPUnit argument %s is not supported: %s", name, reason)
+		}
+		if phpunitValueArgs[name] && !hasValue {
+			i++
+		}
+	}
+	return nil
+}
+
+func (r *Runner) HookTimeoutDuration() (time.Duration, error) {
+	return parseDuration("hook timeout", r.HookTimeout)
+}
+
+func (r *Runner) ShutdownGraceDuration() (time.Duration, error) {
+	return parseDuration("shutdown grace", r.ShutdownGrace)
+}
+
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupPaths returns the cgroup of the current process for each v1
+// controller, keyed by controller name, and for v2 under the empty key.
+func cgroupPaths() map[string]string {
+	paths := make(map[string]string)
+
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return paths
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for controller := range strings.SplitSeq(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// readCgroupFile reads a file from the process's own cgroup, falling back to
+// the root of the hierarchy as seen inside most containers.
+func readCgroupFile(hierarchy, cgroup, name string) (string, bool) {
+	for _, dir := range []string{filepath.Join(hierarchy, cgroup), hierarchy} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	return "", false
+}
+
+func cgroupCPUQuota() float64 {
+	paths := cgroupPaths()
+
+	if v2, ok := paths[""]; ok {
+		if value, ok := readCgroupFile("/sys/fs/cgroup", v2, "cpu.max"); ok {
+			fields := strings.Fields(value)
+			if len(fields) == 2 && fields[0] != "max" {
+				quota, _ := strconv.ParseFloat(fields[0], 64)
+				period, _ := strconv.ParseFloat(fields[1], 64)
+				if quota > 0 && period > 0 {
+					return quota / period
+				}
+			}
+		}
+	}
+
+	if v1, ok := paths["cpu"]; ok {
+		quotaValue, ok1 := readCgroupFile("/sys/fs/cgroup/cpu", v1, "cpu.cfs_quota_us")
+		periodValue, ok2 := readCgroupFile("/sys/fs/cgroup/cpu", v1, "cpu.cfs_period_us")
+		if ok1 && ok2 {
+			quota, _ := strconv.ParseFloat(quotaValue, 64)
+			period, _ := strconv.ParseFloat(periodValue, 64)
+			if quota > 0 && period > 0 {
+				return quota / period
+			}
+		}
+	}
+
+	return 0
+}
+
+// AvailableMemory is the memory that can still be allocated, the lower of the
+// system's available memory and the headroom under any cgroup memory limit.
+func AvailableMemory() uint64 {
+	available := meminfoAvailable()
+
+	if headroom := cgroupMemoryHeadroom(); headroom > 0 && (available == 0 || headroom < available) {
+		available = headroom
+	}
+	return available
+}
+
+func meminfoAvailable() uint64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, _ := strconv.ParseUint(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+func cgroupMemoryHeadroom() uint64 {
+	paths := cgroupPaths()
+
+	var limitValue, usageValue string
+	var ok1, ok2 bool
+	if v2, ok := paths[""]; ok {
+		limitValue, ok1 = readCgroupFile("/sys/fs/cgroup", v2, "memory.max")
+		usageValue, ok2 = readCgroupFile("/sys/fs/cgroup", v2, "memory.current")
+	}
+	if !ok1 || !ok2 {
+		if v1, ok := paths["memory"]; ok {
+			limitValue, ok1 = readCgroupFile("/sys/fs/cgroup/memory", v1, "memory.limit_in_bytes")
+			usageValue, ok2 = readCgroupFile("/sys/fs/cgroup/memory", v1, "memory.usage_in_bytes")
+		}
+	}
+	if !ok1 || !ok2 || limitValue == "max" {
+		return 0
+	}
+
+	limit, err1 := strconv.ParseUint(limitValue, 10, 64)
+	usage, err2 := strconv.ParseUint(usageValue, 10, 64)
+	// cgroup v1 reports an effectively unlimited limit as a huge number.
+	if err1 != nil || err2 != nil || limit >= 1<<62 || usage >= limit {
+		return 0
+	}
+	return limit - usage
+}
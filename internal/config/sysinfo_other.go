@@ -0,0 +1,11 @@
+//go:build !linux
+
+package config
+
+func cgroupCPUQuota() float64 {
+	return 0
+}
+
+func AvailableMemory() uint64 {
+	return 0
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+)
+
+// Theme selects a built-in terminal UI theme by name and overrides any of its
+// colours, given as ANSI numbers or hex codes.
+type Theme struct {
+	Name          string `xml:"name,attr"`
+	Title         string `xml:"title"`
+	TitleText     string `xml:"title-text"`
+	Passed        string `xml:"passed"`
+	Failed        string `xml:"failed"`
+	Running       string `xml:"running"`
+	Skipped       string `xml:"skipped"`
+	Warning       string `xml:"warning"`
+	Dim           string `xml:"dim"`
+	Border        string `xml:"border"`
+	ActiveBorder  string `xml:"active-border"`
+	Selection     string `xml:"selection"`
+	SelectionText string `xml:"selection-text"`
+}
+
+// User holds personal preferences that apply to every project, read from the
+// user config file.
+type User struct {
+	XMLName xml.Name `xml:"runner"`
+	Theme   Theme    `xml:"theme"`
+	Icons   string   `xml:"icons"`
+	Editor  string   `xml:"editor"`
+	Keys    Keys     `xml:"keys"`
+}
+
+// UserConfigPath returns the location of the user config file, or an empty
+// string when there is no user config directory.
+func UserConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "phpunit-parallel", "config.xml")
+}
+
+func ParseUser(path string) (*User, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg User
+	if err := xml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ApplyUser fills in the preferences the runner config leaves unset from the
+// user config.
+func (r *Runner) ApplyUser(u *User) {
+	if r.Theme == (Theme{}) {
+		r.Theme = u.Theme
+	}
+	if r.Icons == "" {
+		r.Icons = u.Icons
+	}
+	if r.Editor == "" {
+		r.Editor = u.Editor
+	}
+	r.Keys = u.Keys
+}
+
+// Keys picks a preset of terminal UI key bindings and rebinds actions to a
+// comma-separated list of keys.
+type Keys struct {
+	Preset   string       `xml:"preset,attr"`
+	Bindings []KeyBinding `xml:"bind"`
+}
+
+type KeyBinding struct {
+	Action string `xml:"action,attr"`
+	Keys   string `xml:",chardata"`
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const autoWorkers = "auto"
+
+// ResolveWorkers turns the workers setting into a worker count. It accepts a
+// number, a percentage of the available CPUs, or "auto", which leaves two CPUs
+// free and, when a per-worker memory estimate is known, fits the workers into
+// the available memory. learnedMemory is used when no estimate is configured.
+func (r *Runner) ResolveWorkers(learnedMemory uint64) (int, error) {
+	cpus := EffectiveCPUs()
+	value := strings.TrimSpace(r.Workers)
+
+	switch {
+	case value == "" || value == autoWorkers:
+		workers := max(cpus-2, 1)
+
+		memory, err := r.WorkerMemoryBytes()
+		if err != nil {
+			return 0, err
+		}
+		if memory == 0 {
+			memory = learnedMemory
+		}
+		if available := AvailableMemory(); memory > 0 && available > 0 {
+			workers = min(workers, max(int(available/memory), 1))
+		}
+		return workers, nil
+
+	case strings.HasSuffix(value, "%"):
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent <= 0 {
+			return 0, fmt.Errorf("invalid workers value %q", r.Workers)
+		}
+		return max(int(math.Round(float64(cpus)*percent/100)), 1), nil
+
+	default:
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers < 1 {
+			return 0, fmt.Errorf("invalid workers value %q, expected a number, a percentage or auto", r.Workers)
+		}
+		return workers, nil
+	}
+}
+
+func (r *Runner) WorkerMemoryBytes() (uint64, error) {
+	if r.WorkerMemory == "" {
+		return 0, nil
+	}
+	size, err := ParseSize(r.WorkerMemory)
+	if err != nil {
+		return 0, fmt.Errorf("invalid worker memory %q: %w", r.WorkerMemory, err)
+	}
+	return size, nil
+}
+
+// ParseSize parses a byte size such as 512M, 1.5G or 2GiB.
+func ParseSize(value string) (uint64, error) {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := 1.0
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size such as 512M or 1G")
+	}
+	return uint64(n * multiplier), nil
+}
+
+// EffectiveCPUs is the number of CPUs available to the process, taking any
+// cgroup CPU quota into account.
+func EffectiveCPUs() int {
+	cpus := runtime.NumCPU()
+	if quota := cgroupCPUQuota(); quota > 0 {
+		cpus = min(cpus, max(int(math.Ceil(quota)), 1))
+	}
+	return cpus
+}
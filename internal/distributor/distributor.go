@@ -1,5 +1,7 @@
 package distributor
 
+import "math/rand/v2"
+
 type TestFile struct {
 	Path  string
 	Suite string
@@ -35,6 +37,24 @@ func RoundRobin(tests []TestFile, workerCount int) Distribution {
 	return Distribution{Workers: buckets}
 }
 
+// Shuffle returns the tests in a random order determined by seed.
+func Shuffle(tests []TestFile, seed int64) []TestFile {
+	shuffled := append([]TestFile(nil), tests...)
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// Shuffle randomises the order of each worker's tests, deriving a separate
+// seed for each worker so that the order doesn't depend on the others.
+func (d Distribution) Shuffle(seed int64) {
+	for i, w := range d.Workers {
+		d.Workers[i].Tests = Shuffle(w.Tests, seed+int64(w.WorkerID)+1)
+	}
+}
+
 func (d Distribution) TestCount() int {
 	count := 0
 	for _, w := range d.Workers {
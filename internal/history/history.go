@@ -0,0 +1,210 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const fileName = "history.jsonl"
+
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+type Run struct {
+	StartedAt  time.Time    `json:"started_at"`
+	Commit     string       `json:"commit,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+	Workers    int          `json:"workers"`
+	Seed       int64        `json:"seed,omitempty"`
+	Tests      []TestResult `json:"tests"`
+	// Files lists the test files each worker ran, in the order they ran.
+	Files map[int][]string `json:"files,omitempty"`
+	// SuiteWallMs is the time from the first test of each suite starting to
+	// its last test finishing, on any worker.
+	SuiteWallMs map[string]int64 `json:"suite_wall_ms,omitempty"`
+	// MaxFilesPerProcess is the number of files each PHPUnit process ran
+	// before it was recycled, or zero when each worker used one process.
+	MaxFilesPerProcess int `json:"max_files_per_process,omitempty"`
+}
+
+type TestResult struct {
+	Name       string `json:"name"`
+	File       string `json:"file,omitempty"`
+	Suite      string `json:"suite,omitempty"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Worker     int    `json:"worker"`
+	Message    string `json:"message,omitempty"`
+}
+
+func (r Run) Duration() time.Duration {
+	return time.Duration(r.DurationMs) * time.Millisecond
+}
+
+func (r Run) Failed() int {
+	count := 0
+	for _, t := range r.Tests {
+		if t.Status == StatusFailed {
+			count++
+		}
+	}
+	return count
+}
+
+// LastFailure returns the most recent run in which a test matching name
+// failed, along with that test's result.
+func LastFailure(runs []Run, name string) (Run, TestResult, bool) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, t := range runs[i].Tests {
+			if t.Status == StatusFailed && matches(t.Name, name) {
+				return runs[i], t, true
+			}
+		}
+	}
+	return Run{}, TestResult{}, false
+}
+
+func matches(testName, name string) bool {
+	name = strings.TrimPrefix(name, `\`)
+	return testName == name || strings.HasPrefix(testName, name+" with data set ")
+}
+
+// Store keeps the most recent runs as one JSON document per line.
+type Store struct {
+	path    string
+	maxRuns int
+}
+
+func NewStore(dir string, maxRuns int) *Store {
+	return &Store{
+		path:    filepath.Join(dir, fileName),
+		maxRuns: maxRuns,
+	}
+}
+
+// Load returns the stored runs, oldest first. A missing store has no runs.
+func (s *Store) Load() ([]Run, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// Append adds a run to the end of the store, then drops the oldest runs once
+// there are more than the limit.
+func (s *Store) Append(run Run) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if s.maxRuns <= 0 {
+		return nil
+	}
+	return s.trim()
+}
+
+// trim rewrites the store without its oldest lines when it holds more than
+// the limit, copying the remaining lines as they are.
+func (s *Store) trim() error {
+	lines, err := s.countLines()
+	if err != nil || lines <= s.maxRuns {
+		return err
+	}
+
+	src, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	r := bufio.NewReader(src)
+	for range lines - s.maxRuns {
+		if _, err := r.ReadBytes('\n'); err != nil {
+			return err
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// countLines returns the number of runs in the store without decoding them.
+func (s *Store) countLines() (int, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	lines := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package history
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// Recorder builds a Run from the TeamCity output of the workers.
+type Recorder struct {
+	mu       sync.Mutex
+	baseDir  string
+	suites   map[string]string
+	started  time.Time
+	running  map[int]map[string]*TestResult
+	finished []TestResult
+	files    map[int][]string
+	// suiteStarts and suiteEnds hold when the first test of each suite started
+	// and when its last test finished, on any worker.
+	suiteStarts map[string]time.Time
+	suiteEnds   map[string]time.Time
+}
+
+// NewRecorder creates a recorder for a run. suites maps absolute test file
+// paths to the name of their test suite.
+func NewRecorder(baseDir string, suites map[string]string) *Recorder {
+	return &Recorder{
+		baseDir:     baseDir,
+		suites:      suites,
+		started:     time.Now(),
+		running:     make(map[int]map[string]*TestResult),
+		files:       make(map[int][]string),
+		suiteStarts: make(map[string]time.Time),
+		suiteEnds:   make(map[string]time.Time),
+	}
+}
+
+func (r *Recorder) Line(workerID int, line string) {
+	if !strings.HasPrefix(line, "##teamcity[") {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	running := r.running[workerID]
+	if running == nil {
+		running = make(map[string]*TestResult)
+		r.running[workerID] = running
+	}
+
+	name := output.ParseTeamCityAttr(line, "name")
+
+	switch {
+	case strings.HasPrefix(line, "##teamcity[testStarted "):
+		file, testName := r.parseLocation(line)
+		if files := r.files[workerID]; file != "" && (len(files) == 0 || files[len(files)-1] != file) {
+			r.files[workerID] = append(files, file)
+		}
+		suite := r.suites[filepath.Join(r.baseDir, file)]
+		if _, ok := r.suiteStarts[suite]; !ok {
+			r.suiteStarts[suite] = time.Now()
+		}
+		running[name] = &TestResult{
+			Name:   testName,
+			File:   file,
+			Suite:  suite,
+			Status: StatusPassed,
+			Worker: workerID,
+		}
+
+	case strings.HasPrefix(line, "##teamcity[testFailed "):
+		if t := running[name]; t != nil {
+			t.Status = StatusFailed
+			t.Message = output.ParseTeamCityAttr(line, "message")
+		}
+
+	case strings.HasPrefix(line, "##teamcity[testIgnored "):
+		if t := running[name]; t != nil && t.Status != StatusFailed {
+			t.Status = StatusSkipped
+			t.Message = output.ParseTeamCityAttr(line, "message")
+		}
+
+	case strings.HasPrefix(line, "##teamcity[testFinished "):
+		if t := running[name]; t != nil {
+			t.DurationMs, _ = strconv.ParseInt(output.ParseTeamCityAttr(line, "duration"), 10, 64)
+			r.finished = append(r.finished, *t)
+			r.suiteEnds[t.Suite] = time.Now()
+			delete(running, name)
+		}
+	}
+}
+
+// parseLocation returns the project-relative file and Class::method name of
+// a test, falling back to the bare test name without a locationHint.
+func (r *Recorder) parseLocation(line string) (string, string) {
+	file, name := output.ParseTeamCityLocation(line)
+	if name == "" {
+		return "", output.ParseTeamCityAttr(line, "name")
+	}
+
+	file = filepath.Clean(file)
+	if rel, err := filepath.Rel(r.baseDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = rel
+	}
+	return file, name
+}
+
+func (r *Recorder) Run(workers int, seed int64) Run {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suiteWall := make(map[string]int64, len(r.suiteEnds))
+	for suite, end := range r.suiteEnds {
+		suiteWall[suite] = end.Sub(r.suiteStarts[suite]).Milliseconds()
+	}
+
+	return Run{
+		StartedAt:   r.started,
+		Commit:      gitCommit(r.baseDir),
+		DurationMs:  time.Since(r.started).Milliseconds(),
+		Workers:     workers,
+		Seed:        seed,
+		Tests:       append([]TestResult(nil), r.finished...),
+		Files:       r.files,
+		SuiteWallMs: suiteWall,
+	}
+}
+
+func gitCommit(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
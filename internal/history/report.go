@@ -0,0 +1,234 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+type TestTrend struct {
+	Name   string
+	File   string
+	Before time.Duration
+	After  time.Duration
+}
+
+func (t TestTrend) Growth() time.Duration {
+	return t.After - t.Before
+}
+
+type FlakyTest struct {
+	Name       string
+	File       string
+	Runs       int
+	Failures   int
+	Flips      int
+	SameCommit bool
+}
+
+type SuiteTime struct {
+	Suite   string
+	Runs    int
+	Average time.Duration
+}
+
+// SlowestGrowing compares the average duration of each test in the older and
+// newer halves of the runs and returns the tests that slowed down the most.
+func SlowestGrowing(runs []Run, limit int) []TestTrend {
+	if len(runs) < 2 {
+		return nil
+	}
+
+	type totals struct {
+		file            string
+		before, after   time.Duration
+		nBefore, nAfter int
+	}
+
+	split := len(runs) / 2
+	byName := make(map[string]*totals)
+	for i, run := range runs {
+		for _, t := range run.Tests {
+			if t.Status != StatusPassed {
+				continue
+			}
+			tt := byName[t.Name]
+			if tt == nil {
+				tt = &totals{}
+				byName[t.Name] = tt
+			}
+			tt.file = t.File
+			d := time.Duration(t.DurationMs) * time.Millisecond
+			if i < split {
+				tt.before += d
+				tt.nBefore++
+			} else {
+				tt.after += d
+				tt.nAfter++
+			}
+		}
+	}
+
+	var trends []TestTrend
+	for name, tt := range byName {
+		if tt.nBefore == 0 || tt.nAfter == 0 {
+			continue
+		}
+		trend := TestTrend{
+			Name:   name,
+			File:   tt.file,
+			Before: tt.before / time.Duration(tt.nBefore),
+			After:  tt.after / time.Duration(tt.nAfter),
+		}
+		if trend.Growth() > 0 {
+			trends = append(trends, trend)
+		}
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Growth() != trends[j].Growth() {
+			return trends[i].Growth() > trends[j].Growth()
+		}
+		return trends[i].Name < trends[j].Name
+	})
+	return truncate(trends, limit)
+}
+
+// Flaky returns the tests whose outcome flipped between passing and failing on
+// the same commit, or at least twice across consecutive runs, so that a test
+// broken once and then fixed is not reported. Tests that flipped on the same
+// commit come first, followed by those that flipped most often.
+func Flaky(runs []Run, limit int) []FlakyTest {
+	type state struct {
+		test    FlakyTest
+		last    string
+		commits map[string]string
+	}
+
+	byName := make(map[string]*state)
+	for _, run := range runs {
+		for _, t := range run.Tests {
+			if t.Status == StatusSkipped {
+				continue
+			}
+			s := byName[t.Name]
+			if s == nil {
+				s = &state{test: FlakyTest{Name: t.Name}, commits: make(map[string]string)}
+				byName[t.Name] = s
+			}
+			s.test.File = t.File
+			s.test.Runs++
+			if t.Status == StatusFailed {
+				s.test.Failures++
+			}
+			if s.last != "" && s.last != t.Status {
+				s.test.Flips++
+			}
+			s.last = t.Status
+			if run.Commit != "" {
+				if prev, ok := s.commits[run.Commit]; ok && prev != t.Status {
+					s.test.SameCommit = true
+				}
+				s.commits[run.Commit] = t.Status
+			}
+		}
+	}
+
+	var flaky []FlakyTest
+	for _, s := range byName {
+		if s.test.SameCommit || s.test.Flips >= 2 {
+			flaky = append(flaky, s.test)
+		}
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].SameCommit != flaky[j].SameCommit {
+			return flaky[i].SameCommit
+		}
+		if flaky[i].Flips != flaky[j].Flips {
+			return flaky[i].Flips > flaky[j].Flips
+		}
+		if flaky[i].Failures != flaky[j].Failures {
+			return flaky[i].Failures > flaky[j].Failures
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+	return truncate(flaky, limit)
+}
+
+// FlakyNames returns the names of every flaky test in the runs.
+func FlakyNames(runs []Run) []string {
+	flaky := Flaky(runs, 0)
+	names := make([]string, len(flaky))
+	for i, t := range flaky {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// SuiteTimes returns the average wall time of each suite, from its first test
+// starting to its last test finishing, over the runs that recorded it.
+func SuiteTimes(runs []Run) []SuiteTime {
+	type totals struct {
+		total time.Duration
+		runs  int
+	}
+
+	bySuite := make(map[string]*totals)
+	for _, run := range runs {
+		for suite, ms := range run.SuiteWallMs {
+			st := bySuite[suite]
+			if st == nil {
+				st = &totals{}
+				bySuite[suite] = st
+			}
+			st.total += time.Duration(ms) * time.Millisecond
+			st.runs++
+		}
+	}
+
+	var suites []SuiteTime
+	for name, st := range bySuite {
+		suites = append(suites, SuiteTime{
+			Suite:   name,
+			Runs:    st.runs,
+			Average: st.total / time.Duration(st.runs),
+		})
+	}
+
+	sort.Slice(suites, func(i, j int) bool {
+		return suites[i].Average > suites[j].Average
+	})
+	return suites
+}
+
+// FileTimes returns the average total test time of each file per run it
+// appeared in.
+func FileTimes(runs []Run) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for _, run := range runs {
+		perRun := make(map[string]time.Duration)
+		for _, t := range run.Tests {
+			if t.File != "" {
+				perRun[t.File] += time.Duration(t.DurationMs) * time.Millisecond
+			}
+		}
+		for file, d := range perRun {
+			totals[file] += d
+			counts[file]++
+		}
+	}
+
+	times := make(map[string]time.Duration, len(totals))
+	for file, total := range totals {
+		times[file] = total / time.Duration(counts[file])
+	}
+	return times
+}
+
+func truncate[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
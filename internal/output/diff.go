@@ -0,0 +1,301 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxDiffCells bounds the size of the LCS table. Larger inputs are shown as a
+// single replacement rather than a minimal diff.
+const maxDiffCells = 4_000_000
+
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+	DiffHunk
+)
+
+type DiffSegment struct {
+	Text    string
+	Changed bool
+}
+
+type DiffLine struct {
+	Op       DiffOp
+	Segments []DiffSegment
+}
+
+func (l DiffLine) Text() string {
+	var b strings.Builder
+	for _, s := range l.Segments {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// Prefix returns the unified diff marker for the line.
+func (l DiffLine) Prefix() string {
+	switch l.Op {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	case DiffHunk:
+		return ""
+	}
+	return " "
+}
+
+// Diff returns a unified diff from expected to actual with the given number
+// of context lines around each change. Changed words are marked within lines
+// that were replaced.
+func Diff(expected, actual string, context int) []DiffLine {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	type edit struct {
+		op   DiffOp
+		text string
+		a, b int
+	}
+
+	var edits []edit
+	ai, bi := 0, 0
+	for _, op := range diffOps(a, b) {
+		switch op {
+		case DiffEqual:
+			edits = append(edits, edit{op, a[ai], ai, bi})
+			ai++
+			bi++
+		case DiffDelete:
+			edits = append(edits, edit{op, a[ai], ai, bi})
+			ai++
+		case DiffInsert:
+			edits = append(edits, edit{op, b[bi], ai, bi})
+			bi++
+		}
+	}
+
+	var lines []DiffLine
+	for i := 0; i < len(edits); {
+		if edits[i].op == DiffEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for end < len(edits) {
+			if edits[end].op != DiffEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == DiffEqual {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = next
+		}
+
+		var aCount, bCount int
+		for _, e := range edits[start:end] {
+			if e.op != DiffInsert {
+				aCount++
+			}
+			if e.op != DiffDelete {
+				bCount++
+			}
+		}
+		lines = append(lines, DiffLine{
+			Op:       DiffHunk,
+			Segments: []DiffSegment{{Text: fmt.Sprintf("@@ -%d,%d +%d,%d @@", edits[start].a+1, aCount, edits[start].b+1, bCount)}},
+		})
+
+		for j := start; j < end; {
+			if edits[j].op == DiffEqual {
+				lines = append(lines, DiffLine{Op: DiffEqual, Segments: []DiffSegment{{Text: edits[j].text}}})
+				j++
+				continue
+			}
+
+			var deleted, inserted []string
+			for ; j < end && edits[j].op == DiffDelete; j++ {
+				deleted = append(deleted, edits[j].text)
+			}
+			for ; j < end && edits[j].op == DiffInsert; j++ {
+				inserted = append(inserted, edits[j].text)
+			}
+			lines = append(lines, replacedLines(deleted, inserted)...)
+		}
+
+		i = end
+	}
+
+	return lines
+}
+
+// replacedLines pairs up deleted and inserted lines and highlights the words
+// that differ between each pair.
+func replacedLines(deleted, inserted []string) []DiffLine {
+	var removed, added []DiffLine
+	for i, text := range deleted {
+		line := DiffLine{Op: DiffDelete, Segments: []DiffSegment{{Text: text}}}
+		if i < len(inserted) {
+			line.Segments, _ = diffWords(text, inserted[i])
+		}
+		removed = append(removed, line)
+	}
+	for i, text := range inserted {
+		line := DiffLine{Op: DiffInsert, Segments: []DiffSegment{{Text: text}}}
+		if i < len(deleted) {
+			_, line.Segments = diffWords(deleted[i], text)
+		}
+		added = append(added, line)
+	}
+	return append(removed, added...)
+}
+
+func diffWords(a, b string) ([]DiffSegment, []DiffSegment) {
+	aw, bw := splitWords(a), splitWords(b)
+
+	var aSegs, bSegs []DiffSegment
+	ai, bi := 0, 0
+	for _, op := range diffOps(aw, bw) {
+		switch op {
+		case DiffEqual:
+			aSegs = appendSegment(aSegs, aw[ai], false)
+			bSegs = appendSegment(bSegs, bw[bi], false)
+			ai++
+			bi++
+		case DiffDelete:
+			aSegs = appendSegment(aSegs, aw[ai], true)
+			ai++
+		case DiffInsert:
+			bSegs = appendSegment(bSegs, bw[bi], true)
+			bi++
+		}
+	}
+	return aSegs, bSegs
+}
+
+func appendSegment(segs []DiffSegment, text string, changed bool) []DiffSegment {
+	if n := len(segs); n > 0 && segs[n-1].Changed == changed {
+		segs[n-1].Text += text
+		return segs
+	}
+	return append(segs, DiffSegment{Text: text, Changed: changed})
+}
+
+// splitWords splits text into runs of word characters, runs of spaces, and
+// individual punctuation characters.
+func splitWords(text string) []string {
+	var words []string
+	kind := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+
+	start := 0
+	prev := -1
+	for i, r := range text {
+		k := kind(r)
+		if i > start && (k != prev || k == 0) {
+			words = append(words, text[start:i])
+			start = i
+		}
+		prev = k
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// diffOps returns the edit script turning a into b, based on their longest
+// common subsequence.
+func diffOps(a, b []string) []DiffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []DiffOp
+	for range prefix {
+		ops = append(ops, DiffEqual)
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)*len(mb) > maxDiffCells {
+		for range ma {
+			ops = append(ops, DiffDelete)
+		}
+		for range mb {
+			ops = append(ops, DiffInsert)
+		}
+	} else {
+		ops = append(ops, lcsOps(ma, mb)...)
+	}
+
+	for range suffix {
+		ops = append(ops, DiffEqual)
+	}
+	return ops
+}
+
+func lcsOps(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	var ops []DiffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffEqual)
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, DiffDelete)
+			i++
+		default:
+			ops = append(ops, DiffInsert)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, DiffDelete)
+	}
+	for ; j < m; j++ {
+		ops = append(ops, DiffInsert)
+	}
+	return ops
+}
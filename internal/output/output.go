@@ -3,6 +3,7 @@ package output
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type StartOptions struct {
@@ -11,42 +12,193 @@ type StartOptions struct {
 	Filter       string
 	Group        string
 	ExcludeGroup string
+	PHPUnitArgs  []string
+	Seed         int64
+	KnownFlaky   []string
+	Quarantine   []string
+	Editor       string
+	// BaseDir is the project directory that file paths are relative to.
+	BaseDir string
+	// WorkerFiles holds the project-relative test files assigned to each
+	// worker, in the order they were assigned.
+	WorkerFiles map[int][]string
+	// FileTimes holds the recorded test time of each project-relative file,
+	// used to estimate how long the run will take.
+	FileTimes map[string]time.Duration
+}
+
+type HookResult struct {
+	Hook     string
+	Duration time.Duration
+	Output   string
+	LogPath  string
+	Err      error
+}
+
+type ProcessInfo struct {
+	PID        int
+	Command    string
+	ConfigPath string
+}
+
+type ResourceUsage struct {
+	RSS         uint64
+	PeakRSS     uint64
+	CPUTime     time.Duration
+	CurrentFile string
+	// FilePeak is the peak resident memory seen while CurrentFile was running.
+	FilePeak uint64
 }
 
 type Output interface {
 	Start(opts StartOptions)
 	WorkerStart(workerID, testCount int)
+	WorkerHook(workerID int, result HookResult)
+	WorkerBatch(workerID, fileCount int)
+	WorkerProcess(workerID int, process ProcessInfo)
 	WorkerLine(workerID int, line string)
+	WorkerUsage(workerID int, usage ResourceUsage)
 	WorkerComplete(workerID int, err error)
 	CleanupProgress(completed, total int)
 	Finish()
 	SetOnCancel(fn func())
+	Draining()
+	Cancel()
 }
 
+// Rerunner is implemented by outputs that let tests be run again once the run
+// has finished.
+type Rerunner interface {
+	SetOnRerun(fn func(test RerunRequest) (RerunResult, error))
+}
+
+type RerunRequest struct {
+	Name  string
+	Key   string // Method name as reported by PHPUnit, including any data set
+	File  string
+	Suite string
+}
+
+type RerunResult struct {
+	Failed     bool
+	Skipped    bool
+	Message    string
+	Details    string
+	Expected   string
+	Actual     string
+	Comparison bool
+	Duration   time.Duration
+}
+
+// TestSet matches test names of the form Class::method. Entries may also name
+// a whole class, and a leading namespace separator is ignored.
+type TestSet map[string]bool
+
+func NewTestSet(names []string) TestSet {
+	set := make(TestSet, len(names))
+	for _, name := range names {
+		set[strings.TrimPrefix(name, `\`)] = true
+	}
+	return set
+}
+
+func (s TestSet) Has(name string) bool {
+	name = strings.TrimPrefix(name, `\`)
+	if s[name] {
+		return true
+	}
+	class, method, found := strings.Cut(name, "::")
+	if !found {
+		return false
+	}
+	if s[class] {
+		return true
+	}
+	if method, _, found := strings.Cut(method, " with data set "); found {
+		return s[class+"::"+method]
+	}
+	return false
+}
+
+// EstimateTestCount extrapolates a worker's total test count from the batches
+// PHPUnit has reported so far, assuming unreported files hold as many tests
+// on average as the reported ones.
+func EstimateTestCount(countedTests, countedFiles, totalFiles int) int {
+	remaining := totalFiles - countedFiles
+	if countedFiles <= 0 || remaining <= 0 {
+		return countedTests
+	}
+	return countedTests + remaining*countedTests/countedFiles
+}
+
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseTeamCityAttr returns the unescaped value of attr in a TeamCity service
+// message, or an empty string when the message has no such attribute.
 func ParseTeamCityAttr(line, attr string) string {
-	prefix := attr + "='"
-	start := strings.Index(line, prefix)
-	if start < 0 {
-		return ""
+	i := 0
+	for {
+		eq := strings.Index(line[i:], "='")
+		if eq < 0 {
+			return ""
+		}
+		name := line[i : i+eq]
+		name = name[strings.LastIndexAny(name, " [")+1:]
+		value, next := unescapeTeamCityValue(line, i+eq+2)
+		if name == attr {
+			return value
+		}
+		i = next
 	}
-	start += len(prefix)
+}
 
-	end := start
-	for end < len(line) {
-		if line[end] == '\'' && (end == start || line[end-1] != '|') {
-			break
+// unescapeTeamCityValue unescapes the quoted value that starts at start in a
+// single pass, returning it along with the index just past its closing quote.
+func unescapeTeamCityValue(line string, start int) (string, int) {
+	var b strings.Builder
+	for i := start; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\'':
+			return b.String(), i + 1
+		case c == '|' && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(line[i])
+			}
+		default:
+			b.WriteByte(c)
 		}
-		end++
 	}
+	return b.String(), len(line)
+}
 
-	value := line[start:end]
-	value = strings.ReplaceAll(value, "|'", "'")
-	value = strings.ReplaceAll(value, "|n", "\n")
-	value = strings.ReplaceAll(value, "|r", "\r")
-	value = strings.ReplaceAll(value, "||", "|")
-	value = strings.ReplaceAll(value, "|[", "[")
-	value = strings.ReplaceAll(value, "|]", "]")
-	return value
+var teamCityEscaper = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+)
+
+func EscapeTeamCityValue(value string) string {
+	return teamCityEscaper.Replace(value)
 }
 
 func ParseTeamCityCount(line string) *int {
@@ -65,6 +217,26 @@ func ParseTeamCityError(line string) (name, message, details string) {
 	return ParseTeamCityAttr(line, "name"), ParseTeamCityAttr(line, "message"), ParseTeamCityAttr(line, "details")
 }
 
+// ParseTeamCityComparison returns the expected and actual values of a failed
+// equality assertion, which PHPUnit reports with type='comparisonFailure'.
+func ParseTeamCityComparison(line string) (expected, actual string, ok bool) {
+	if ParseTeamCityAttr(line, "type") != "comparisonFailure" {
+		return "", "", false
+	}
+	return ParseTeamCityAttr(line, "expected"), ParseTeamCityAttr(line, "actual"), true
+}
+
+// ParseTeamCityLocation splits a locationHint of the form
+// php_qn://<file>::\<class>::<method> into the file and Class::method.
+func ParseTeamCityLocation(line string) (file, test string) {
+	hint, ok := strings.CutPrefix(ParseTeamCityAttr(line, "locationHint"), "php_qn://")
+	if !ok {
+		return "", ""
+	}
+	file, test, _ = strings.Cut(hint, "::")
+	return file, strings.TrimPrefix(test, `\`)
+}
+
 func ParseTeamCityTestName(line string) string {
 	locationHint := ParseTeamCityAttr(line, "locationHint")
 	if locationHint != "" {
@@ -0,0 +1,40 @@
+package output
+
+import "testing"
+
+func TestParseTeamCityAttrRoundTrip(t *testing.T) {
+	values := []string{
+		"",
+		"plain",
+		"it's",
+		"ends with a pipe |",
+		"ends with a quote '",
+		"literal |n is not a newline",
+		"line one\nline two\r\n",
+		"[brackets] and || pipes",
+		"|'|n|r|[|]||",
+	}
+
+	for _, value := range values {
+		line := "##teamcity[testFailed name='" + EscapeTeamCityValue(value) + "' message='" + EscapeTeamCityValue(value) + "' flowId='1']"
+		if got := ParseTeamCityAttr(line, "name"); got != value {
+			t.Errorf("name: got %q, want %q", got, value)
+		}
+		if got := ParseTeamCityAttr(line, "message"); got != value {
+			t.Errorf("message: got %q, want %q", got, value)
+		}
+		if got := ParseTeamCityAttr(line, "flowId"); got != "1" {
+			t.Errorf("flowId after %q: got %q, want %q", value, got, "1")
+		}
+	}
+}
+
+func TestParseTeamCityAttrMatchesWholeNames(t *testing.T) {
+	line := "##teamcity[testStarted testName='wrong' message='name=|'also wrong|'' name='right']"
+	if got := ParseTeamCityAttr(line, "name"); got != "right" {
+		t.Errorf("got %q, want %q", got, "right")
+	}
+	if got := ParseTeamCityAttr(line, "missing"); got != "" {
+		t.Errorf("got %q for a missing attribute", got)
+	}
+}
@@ -1,10 +1,13 @@
 package output
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 var flowIdPattern = regexp.MustCompile(` flowId='[^']*'`)
@@ -18,22 +21,39 @@ type teamCitySuite struct {
 type teamCityWorker struct {
 	suites        []teamCitySuite
 	skippedSuites map[string]bool
+	testNames     map[string]string
 }
 
 type TeamCityOutput struct {
 	mu            sync.Mutex
 	workers       map[int]*teamCityWorker
 	startedSuites map[string]bool
+	hookDurations map[string]time.Duration
+	filePeaks     map[string]uint64
+	quarantine    TestSet
+	quarantined   []string
 }
 
 func NewTeamCityOutput() *TeamCityOutput {
 	return &TeamCityOutput{
 		workers:       make(map[int]*teamCityWorker),
 		startedSuites: make(map[string]bool),
+		hookDurations: make(map[string]time.Duration),
+		filePeaks:     make(map[string]uint64),
 	}
 }
 
-func (t *TeamCityOutput) Start(opts StartOptions) {}
+func (t *TeamCityOutput) Start(opts StartOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.quarantine = NewTestSet(opts.Quarantine)
+
+	if opts.Seed != 0 {
+		text := fmt.Sprintf("Random order seed: %d (reproduce with --seed %d --workers %d)", opts.Seed, opts.Seed, opts.WorkerCount)
+		fmt.Printf("##teamcity[message text='%s']\n", EscapeTeamCityValue(text))
+	}
+}
 
 func (t *TeamCityOutput) WorkerStart(workerID, testCount int) {
 	t.mu.Lock()
@@ -41,9 +61,33 @@ func (t *TeamCityOutput) WorkerStart(workerID, testCount int) {
 
 	t.workers[workerID] = &teamCityWorker{
 		skippedSuites: make(map[string]bool),
+		testNames:     make(map[string]string),
+	}
+}
+
+func (t *TeamCityOutput) WorkerHook(workerID int, result HookResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hookDurations[result.Hook] += result.Duration
+
+	if result.Err == nil {
+		return
+	}
+
+	text := fmt.Sprintf("Worker %d: %s failed: %s", workerID+1, result.Hook, result.Err)
+	details := result.Output
+	if result.LogPath != "" {
+		details += "\n\nFull log: " + result.LogPath
 	}
+	fmt.Printf("##teamcity[message text='%s' errorDetails='%s' status='ERROR']\n",
+		EscapeTeamCityValue(text), EscapeTeamCityValue(details))
 }
 
+func (t *TeamCityOutput) WorkerBatch(workerID, fileCount int) {}
+
+func (t *TeamCityOutput) WorkerProcess(workerID int, process ProcessInfo) {}
+
 func (t *TeamCityOutput) WorkerLine(workerID int, line string) {
 	if !strings.HasPrefix(line, "##teamcity") {
 		return
@@ -70,6 +114,16 @@ func (t *TeamCityOutput) WorkerLine(workerID int, line string) {
 		if len(w.suites) > 0 {
 			w.suites[len(w.suites)-1].hasTests = true
 		}
+		w.testNames[ParseTeamCityAttr(line, "name")] = ParseTeamCityTestName(line)
+		t.bufferLine(w, cleanLine)
+
+	case strings.HasPrefix(line, "##teamcity[testFailed "):
+		name := ParseTeamCityAttr(line, "name")
+		if testName := w.testNames[name]; t.quarantine.Has(testName) {
+			t.quarantined = append(t.quarantined, strings.TrimPrefix(testName, `\`))
+			cleanLine = fmt.Sprintf("##teamcity[testIgnored name='%s' message='%s']",
+				EscapeTeamCityValue(name), EscapeTeamCityValue("Quarantined: "+ParseTeamCityAttr(line, "message")))
+		}
 		t.bufferLine(w, cleanLine)
 
 	default:
@@ -123,6 +177,15 @@ func (t *TeamCityOutput) bufferLine(w *teamCityWorker, line string) {
 	}
 }
 
+func (t *TeamCityOutput) WorkerUsage(workerID int, usage ResourceUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if file := usage.CurrentFile; file != "" {
+		t.filePeaks[file] = max(t.filePeaks[file], usage.FilePeak)
+	}
+}
+
 func (t *TeamCityOutput) WorkerComplete(workerID int, err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -147,4 +210,76 @@ func (t *TeamCityOutput) CleanupProgress(completed, total int) {}
 
 func (t *TeamCityOutput) SetOnCancel(fn func()) {}
 
-func (t *TeamCityOutput) Finish() {}
+func (t *TeamCityOutput) Draining() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Println("##teamcity[message text='Stopping after current tests' status='WARNING']")
+}
+
+func (t *TeamCityOutput) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Println("##teamcity[message text='Run cancelled, stopping workers' status='WARNING']")
+}
+
+func (t *TeamCityOutput) Finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.printMemoryUsage()
+	t.printHookDurations()
+	t.printQuarantined()
+}
+
+// printQuarantined lists the quarantined tests that failed. Their failures
+// are reported as ignored tests so they do not fail the build.
+func (t *TeamCityOutput) printQuarantined() {
+	if len(t.quarantined) == 0 {
+		return
+	}
+
+	text := fmt.Sprintf("%d quarantined test(s) failed: %s", len(t.quarantined), strings.Join(t.quarantined, ", "))
+	fmt.Printf("##teamcity[message text='%s' status='WARNING']\n", EscapeTeamCityValue(text))
+}
+
+func (t *TeamCityOutput) printMemoryUsage() {
+	if len(t.filePeaks) == 0 {
+		return
+	}
+
+	files := make([]string, 0, len(t.filePeaks))
+	for file := range t.filePeaks {
+		files = append(files, file)
+	}
+	slices.SortFunc(files, func(a, b string) int {
+		return cmp.Compare(t.filePeaks[b], t.filePeaks[a])
+	})
+	files = files[:min(len(files), 5)]
+
+	parts := make([]string, len(files))
+	for i, file := range files {
+		parts[i] = fmt.Sprintf("%s %s", file, FormatBytes(t.filePeaks[file]))
+	}
+	fmt.Printf("##teamcity[message text='%s']\n", EscapeTeamCityValue("Peak memory by test file: "+strings.Join(parts, ", ")))
+	fmt.Printf("##teamcity[buildStatisticValue key='phpunitParallel.peakMemory' value='%d']\n", t.filePeaks[files[0]])
+}
+
+func (t *TeamCityOutput) printHookDurations() {
+	if len(t.hookDurations) == 0 {
+		return
+	}
+
+	hooks := make([]string, 0, len(t.hookDurations))
+	for hook := range t.hookDurations {
+		hooks = append(hooks, hook)
+	}
+	slices.Sort(hooks)
+
+	parts := make([]string, len(hooks))
+	for i, hook := range hooks {
+		parts[i] = fmt.Sprintf("%s %s", hook, t.hookDurations[hook].Round(time.Millisecond))
+	}
+	fmt.Printf("##teamcity[message text='%s']\n", EscapeTeamCityValue("Hook time: "+strings.Join(parts, ", ")))
+}
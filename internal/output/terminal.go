@@ -28,6 +28,9 @@ type terminalWorker struct {
 	hasActualTestCount bool
 	testsCompleted     int
 	testsFailed        int
+	batchFiles         int
+	countedFiles       int
+	countedTests       int
 	completed          bool
 	err                error
 	failedTestNames    map[string]bool
@@ -53,6 +56,7 @@ type TerminalOutput struct {
 	showErrors         bool
 	oldTermState       *term.State
 	onCancel           func()
+	draining           bool
 }
 
 func NewTerminalOutput() *TerminalOutput {
@@ -72,6 +76,9 @@ func (t *TerminalOutput) Start(opts StartOptions) {
 	t.workerCount = opts.WorkerCount
 
 	fmt.Printf("Running %d test files across %d workers\n", opts.TestCount, opts.WorkerCount)
+	if opts.Seed != 0 {
+		fmt.Printf("Random order seed: %d\n", opts.Seed)
+	}
 	fmt.Printf("%sPress 'e' to show errors%s\n\n", colorDim, colorReset)
 
 	t.startKeyboardListener()
@@ -103,12 +110,8 @@ func (t *TerminalOutput) startKeyboardListener() {
 					t.render()
 					t.mu.Unlock()
 				}
-				if buf[0] == 3 {
-					t.restoreTerminal()
-					if t.onCancel != nil {
-						t.onCancel()
-					}
-					os.Exit(130)
+				if buf[0] == 3 && t.onCancel != nil {
+					t.onCancel()
 				}
 			}
 		}
@@ -151,6 +154,36 @@ func (t *TerminalOutput) WorkerStart(workerID, testCount int) {
 	t.render()
 }
 
+func (t *TerminalOutput) WorkerHook(workerID int, result HookResult) {
+	if result.Err == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	details := result.Output
+	if result.LogPath != "" {
+		details += "\nFull log: " + result.LogPath
+	}
+	t.errors = append(t.errors, terminalError{
+		testName: fmt.Sprintf("Worker %d: %s", workerID+1, result.Hook),
+		message:  result.Err.Error(),
+		details:  details,
+	})
+}
+
+func (t *TerminalOutput) WorkerBatch(workerID, fileCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if w := t.workers[workerID]; w != nil {
+		w.batchFiles = fileCount
+	}
+}
+
+func (t *TerminalOutput) WorkerProcess(workerID int, process ProcessInfo) {}
+
 func (t *TerminalOutput) WorkerLine(workerID int, line string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -163,12 +196,15 @@ func (t *TerminalOutput) WorkerLine(workerID int, line string) {
 	switch {
 	case strings.HasPrefix(line, "##teamcity[testCount "):
 		if count := ParseTeamCityCount(line); count != nil {
+			w.countedTests += *count
+			w.countedFiles += w.batchFiles
+			total := EstimateTestCount(w.countedTests, w.countedFiles, w.testFileCount)
 			if !w.hasActualTestCount {
-				t.testCount = t.testCount - w.testFileCount + *count
+				t.testCount = t.testCount - w.testFileCount + total
 			} else {
-				t.testCount = t.testCount - w.testCount + *count
+				t.testCount = t.testCount - w.testCount + total
 			}
-			w.testCount = *count
+			w.testCount = total
 			w.hasActualTestCount = true
 			t.hasActualTestCount = true
 		}
@@ -188,6 +224,8 @@ func (t *TerminalOutput) WorkerLine(workerID int, line string) {
 	}
 }
 
+func (t *TerminalOutput) WorkerUsage(workerID int, usage ResourceUsage) {}
+
 func (t *TerminalOutput) WorkerComplete(workerID int, err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -211,6 +249,22 @@ func (t *TerminalOutput) SetOnCancel(fn func()) {
 	t.onCancel = fn
 }
 
+func (t *TerminalOutput) Draining() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.draining = true
+	t.render()
+}
+
+func (t *TerminalOutput) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.restoreTerminal()
+	fmt.Fprintln(os.Stderr, "Cancelling, stopping workers...")
+}
+
 func (t *TerminalOutput) Finish() {
 	close(t.done)
 
@@ -261,6 +315,9 @@ func (t *TerminalOutput) render() {
 		progressBar = t.buildProgressBar(0, 0, 0, 30)
 		progressText = fmt.Sprintf("%s%d test files%s", colorBold, t.testFileCount, colorReset)
 	}
+	if t.draining {
+		progressText += fmt.Sprintf(" %sStopping after current tests...%s", colorYellow, colorReset)
+	}
 	t.printLine(fmt.Sprintf("  %s %s", progressBar, progressText))
 	t.printLine("")
 
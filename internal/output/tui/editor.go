@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+var framePattern = regexp.MustCompile(`(/?[^\s:'"()]+\.php):(\d+)`)
+
+// sourceFrame returns the first file:line frame of the details that belongs to
+// the project, ignoring frames in vendor.
+func sourceFrame(details, cwd string) (string, int, bool) {
+	for _, match := range framePattern.FindAllStringSubmatch(details, -1) {
+		file := match[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(cwd, file)
+		}
+		rel, err := filepath.Rel(cwd, file)
+		if err != nil || strings.HasPrefix(rel, "..") || strings.HasPrefix(rel, "vendor"+string(filepath.Separator)) {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		line, _ := strconv.Atoi(match[2])
+		return file, line, true
+	}
+	return "", 0, false
+}
+
+// editorCommand builds the command opening file at line. The template may use
+// {file} and {line}; without one, $VISUAL or $EDITOR is started with +line.
+func editorCommand(template, file string, line int) (*exec.Cmd, error) {
+	if template == "" {
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			return nil, errors.New("no editor configured, set $VISUAL, $EDITOR or --editor")
+		}
+		template = editor + " +{line} {file}"
+	}
+	if !strings.Contains(template, "{file}") {
+		template += " {file}"
+	}
+
+	script := strings.NewReplacer(
+		"{file}", output.ShellQuote(file),
+		"{line}", strconv.Itoa(line),
+	).Replace(template)
+	return exec.Command("sh", "-c", script), nil
+}
+
+// openInEditor suspends the terminal UI while the editor runs, which would
+// block the workers' updates, so it is only allowed once the run is over.
+func (m *Model) openInEditor() (tea.Model, tea.Cmd) {
+	var details string
+	if m.phase != PhaseComplete && m.phase != PhaseExploring {
+		return m, nil
+	}
+	if m.phase == PhaseExploring {
+		if n := m.selectedTreeNode(); n != nil && n.test != nil {
+			details = n.test.ErrorDetails
+		}
+	} else if e := m.selectedError(); e != nil && m.activePanel == PanelErrors {
+		details = e.Details
+	}
+	if details == "" {
+		return m, nil
+	}
+
+	file, line, ok := sourceFrame(details, m.cwd)
+	if !ok {
+		return m, m.notify("No project file in the stack trace")
+	}
+	cmd, err := editorCommand(m.editor, file, line)
+	if err != nil {
+		return m, m.notify(err.Error())
+	}
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return EditorClosedMsg{Err: err}
+	})
+}
+
+// hyperlink turns a rendered stack frame into an OSC 8 hyperlink to the file
+// it mentions, so terminals that support it can open the file on click.
+func (m *Model) hyperlink(frame, rendered string) string {
+	match := framePattern.FindStringSubmatch(frame)
+	if match == nil {
+		return rendered
+	}
+	file := match[1]
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(m.cwd, file)
+	}
+	return fmt.Sprintf("\x1b]8;;file://%s\x1b\\%s\x1b]8;;\x1b\\", file, rendered)
+}
@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// errorRow is a line of the errors panel: a single error, or a group of
+// errors sharing the same message when grouping is enabled.
+type errorRow struct {
+	index int
+	group *errorGroup
+	depth int
+}
+
+type errorGroup struct {
+	message string
+	indexes []int
+}
+
+// errorRows returns the rows of the errors panel after applying the search
+// and grouping.
+func (m *Model) errorRows() []errorRow {
+	var matching []int
+	for i := range m.errors {
+		if m.matchesError(m.errors[i]) {
+			matching = append(matching, i)
+		}
+	}
+
+	if !m.groupErrors {
+		rows := make([]errorRow, len(matching))
+		for i, index := range matching {
+			rows[i] = errorRow{index: index}
+		}
+		return rows
+	}
+
+	var groups []*errorGroup
+	byMessage := make(map[string]*errorGroup)
+	for _, index := range matching {
+		message := m.errors[index].Message
+		g := byMessage[message]
+		if g == nil {
+			g = &errorGroup{message: message}
+			byMessage[message] = g
+			groups = append(groups, g)
+		}
+		g.indexes = append(g.indexes, index)
+	}
+
+	var rows []errorRow
+	for _, g := range groups {
+		if len(g.indexes) == 1 {
+			rows = append(rows, errorRow{index: g.indexes[0]})
+			continue
+		}
+		rows = append(rows, errorRow{index: -1, group: g})
+		if m.expandedGroups[g.message] {
+			for _, index := range g.indexes {
+				rows = append(rows, errorRow{index: index, depth: 1})
+			}
+		}
+	}
+	return rows
+}
+
+// matchesError reports whether an error matches the search by test name,
+// message or details, which include the file of each stack frame.
+func (m *Model) matchesError(e ErrorEntry) bool {
+	if m.errorSearch == "" {
+		return true
+	}
+
+	if m.errorRegex {
+		if m.errorPattern == nil {
+			return true
+		}
+		re := m.errorPattern
+		return re.MatchString(e.TestName) || re.MatchString(e.Message) || re.MatchString(e.Details)
+	}
+
+	query := strings.ToLower(m.errorSearch)
+	return strings.Contains(strings.ToLower(e.TestName), query) ||
+		strings.Contains(strings.ToLower(e.Message), query) ||
+		strings.Contains(strings.ToLower(e.Details), query)
+}
+
+// setErrorSearch updates the search and compiles it once when it is a regex,
+// rather than for every error on every render.
+func (m *Model) setErrorSearch(search string, regex bool) {
+	m.errorSearch = search
+	m.errorRegex = regex
+	m.errorPattern, m.errorPatternErr = nil, nil
+	if regex && search != "" {
+		m.errorPattern, m.errorPatternErr = regexp.Compile("(?i)" + search)
+	}
+}
+
+// invalidErrorSearch reports whether the search is a regex that doesn't
+// compile, in which case every error is shown.
+func (m *Model) invalidErrorSearch() bool {
+	return m.errorRegex && m.errorPatternErr != nil
+}
+
+// selectedError returns the error under the cursor, or nil when the cursor is
+// on a group.
+func (m *Model) selectedError() *ErrorEntry {
+	rows := m.errorRows()
+	if m.errorCursor < 0 || m.errorCursor >= len(rows) || rows[m.errorCursor].group != nil {
+		return nil
+	}
+	return &m.errors[rows[m.errorCursor].index]
+}
+
+// jumpToMatch moves the cursor to the next or previous error, skipping the
+// headers of expanded groups and wrapping around at either end of the list.
+func (m *Model) jumpToMatch(delta int) {
+	rows := m.errorRows()
+	if len(rows) == 0 {
+		return
+	}
+	for i := 1; i <= len(rows); i++ {
+		next := ((m.errorCursor+delta*i)%len(rows) + len(rows)) % len(rows)
+		if rows[next].group == nil || !m.expandedGroups[rows[next].group.message] {
+			m.errorCursor = next
+			return
+		}
+	}
+}
+
+func (m *Model) handleErrorSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.errorSearching = false
+	case tea.KeyEsc:
+		m.errorSearching = false
+		m.setErrorSearch("", m.errorRegex)
+	case tea.KeyCtrlR:
+		m.setErrorSearch(m.errorSearch, !m.errorRegex)
+	case tea.KeyBackspace:
+		if r := []rune(m.errorSearch); len(r) > 0 {
+			m.setErrorSearch(string(r[:len(r)-1]), m.errorRegex)
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.setErrorSearch(m.errorSearch+string(msg.Runes), m.errorRegex)
+	}
+	m.errorCursor = 0
+	m.errorOffset = 0
+	return m, nil
+}
@@ -0,0 +1,100 @@
+package tui
+
+import "time"
+
+// averageFileTime returns the mean recorded time of the files being run, used
+// for the files with no history. It is zero when none of them have history.
+func averageFileTime(files map[int][]string, times map[string]time.Duration) time.Duration {
+	var total time.Duration
+	var known int
+	for _, list := range files {
+		for _, file := range list {
+			if d, ok := times[file]; ok {
+				total += d
+				known++
+			}
+		}
+	}
+	if known == 0 {
+		return 0
+	}
+	return total / time.Duration(known)
+}
+
+// estimating reports whether there is history to weight progress by.
+func (m *Model) estimating() bool {
+	return m.averageFileTime > 0
+}
+
+// expectedTime returns how long a file is expected to take. Every file counts
+// for at least a millisecond so that instant files still show progress.
+func (m *Model) expectedTime(file string) time.Duration {
+	d, ok := m.fileTimes[file]
+	if !ok {
+		d = m.averageFileTime
+	}
+	return max(d, time.Millisecond)
+}
+
+// workerWork returns the expected time of the worker's files and how much of
+// it is done. The running file counts for the time spent in it so far, up to
+// its expected time.
+func (m *Model) workerWork(w *WorkerNode) (done, total time.Duration) {
+	for i, file := range w.Files {
+		expected := m.expectedTime(file)
+		total += expected
+		switch w.fileStatus(i) {
+		case fileDone:
+			done += expected
+		case fileRunning:
+			done += min(time.Since(w.FileStarted), expected)
+		}
+	}
+	if w.Finished {
+		done = total
+	}
+	return done, total
+}
+
+// workerETA estimates the time the worker has left by scaling its remaining
+// expected time by how fast it has run compared to the history. Hook time is
+// left out as hooks don't run again.
+func (m *Model) workerETA(w *WorkerNode) time.Duration {
+	if w.Finished {
+		return 0
+	}
+	done, total := m.workerWork(w)
+	remaining := total - done
+	if elapsed := m.getElapsed() - w.HookTime; done > 0 && elapsed > 0 {
+		remaining = time.Duration(float64(remaining) * float64(elapsed) / float64(done))
+	}
+	return remaining
+}
+
+// estimate returns the fraction of the run's expected time that is done and
+// the time left, which is that of the slowest worker.
+func (m *Model) estimate() (fraction float64, remaining time.Duration) {
+	var done, total time.Duration
+	for _, w := range m.workers {
+		d, t := m.workerWork(w)
+		done += d
+		total += t
+		remaining = max(remaining, m.workerETA(w))
+	}
+	if total == 0 {
+		return 0, remaining
+	}
+	return float64(done) / float64(total), remaining
+}
+
+// weightedProgressBar draws a progress bar filled to the given fraction, with
+// the failed share of the completed tests in red.
+func (m *Model) weightedProgressBar(fraction float64, completed, failed, width int, dimmed bool) string {
+	const scale = 1000
+	filled := int(fraction * scale)
+	scaledFailed := 0
+	if completed > 0 {
+		scaledFailed = failed * filled / completed
+	}
+	return m.buildProgressBar(filled, scaledFailed, scale, width, dimmed)
+}
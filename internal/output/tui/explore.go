@@ -0,0 +1,509 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// slowTestThreshold is the duration from which the explorer treats a test
+// as slow.
+const slowTestThreshold = 500 * time.Millisecond
+
+type treeKind int
+
+const (
+	treeSuite treeKind = iota
+	treeFile
+	treeMethod
+	treeDataSet
+)
+
+func (k treeKind) String() string {
+	switch k {
+	case treeSuite:
+		return "Suite:"
+	case treeFile:
+		return "File:"
+	case treeMethod:
+		return "Method:"
+	}
+	return "Data set:"
+}
+
+type treeFilter int
+
+const (
+	filterAll treeFilter = iota
+	filterFailed
+	filterSkipped
+	filterSlow
+)
+
+func (f treeFilter) String() string {
+	switch f {
+	case filterFailed:
+		return "failed"
+	case filterSkipped:
+		return "skipped"
+	case filterSlow:
+		return fmt.Sprintf("slow (≥%s)", formatDuration(slowTestThreshold))
+	}
+	return "all"
+}
+
+type treeNode struct {
+	id       string
+	kind     treeKind
+	label    string
+	test     *TestNode
+	children []*treeNode
+}
+
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// treeStats aggregates the tests below a node.
+type treeStats struct {
+	tests    int
+	failed   int
+	skipped  int
+	duration time.Duration
+}
+
+func (n *treeNode) stats() treeStats {
+	if n.test != nil && len(n.children) == 0 {
+		s := treeStats{tests: 1, duration: n.test.Duration}
+		switch n.test.Status {
+		case StatusFailed:
+			s.failed = 1
+		case StatusSkipped:
+			s.skipped = 1
+		}
+		return s
+	}
+
+	var s treeStats
+	for _, c := range n.children {
+		cs := c.stats()
+		s.tests += cs.tests
+		s.failed += cs.failed
+		s.skipped += cs.skipped
+		s.duration += cs.duration
+	}
+	return s
+}
+
+// buildTree groups every executed test by suite, file and method, with data
+// sets of a method as its children.
+func (m *Model) buildTree() []*treeNode {
+	var suites []*treeNode
+	index := make(map[string]*treeNode)
+
+	child := func(parent *[]*treeNode, id string, kind treeKind, label string) *treeNode {
+		if n, ok := index[id]; ok {
+			return n
+		}
+		n := &treeNode{id: id, kind: kind, label: label}
+		index[id] = n
+		*parent = append(*parent, n)
+		return n
+	}
+
+	for _, workerID := range m.workerOrder {
+		for _, t := range m.workers[workerID].Tests {
+			suiteName := t.Suite
+			if suiteName == "" {
+				suiteName = "Tests"
+			}
+			suite := child(&suites, suiteName, treeSuite, suiteName)
+
+			file := t.File
+			if file == "" {
+				file, _, _ = strings.Cut(strings.TrimPrefix(t.Name, `\`), "::")
+			}
+			fileNode := child(&suite.children, suite.id+"\x00"+file, treeFile, file)
+
+			_, method, found := strings.Cut(t.Name, "::")
+			if !found {
+				method = t.Name
+			}
+			method, dataSet, hasDataSet := strings.Cut(method, " with data set ")
+			methodNode := child(&fileNode.children, fileNode.id+"\x00"+method, treeMethod, method)
+			if !hasDataSet {
+				methodNode.test = t
+				continue
+			}
+			dataSetNode := child(&methodNode.children, methodNode.id+"\x00"+dataSet, treeDataSet, dataSet)
+			dataSetNode.test = t
+		}
+	}
+
+	sort.SliceStable(suites, func(i, j int) bool { return suites[i].label < suites[j].label })
+	for _, suite := range suites {
+		sort.SliceStable(suite.children, func(i, j int) bool { return suite.children[i].label < suite.children[j].label })
+	}
+	return suites
+}
+
+func (m *Model) enterExplore() {
+	m.tree = m.buildTree()
+	m.treeExpanded = make(map[string]bool)
+	for _, suite := range m.tree {
+		m.treeExpanded[suite.id] = true
+		for _, file := range suite.children {
+			if file.stats().failed > 0 {
+				m.treeExpanded[file.id] = true
+			}
+		}
+	}
+	m.treeCursor = 0
+	m.treeOffset = 0
+	m.phase = PhaseExploring
+}
+
+func (m *Model) leaveExplore() {
+	m.phase = PhaseComplete
+	m.treeSearching = false
+}
+
+// visible reports whether a test matches the status filter and search.
+func (m *Model) visible(t *TestNode) bool {
+	switch m.treeFilter {
+	case filterFailed:
+		if t.Status != StatusFailed {
+			return false
+		}
+	case filterSkipped:
+		if t.Status != StatusSkipped {
+			return false
+		}
+	case filterSlow:
+		if t.Duration < slowTestThreshold {
+			return false
+		}
+	}
+
+	if m.treeSearch == "" {
+		return true
+	}
+	query := strings.ToLower(m.treeSearch)
+	return strings.Contains(strings.ToLower(t.Name), query) || strings.Contains(strings.ToLower(t.File), query)
+}
+
+func (m *Model) filtering() bool {
+	return m.treeFilter != filterAll || m.treeSearch != ""
+}
+
+// treeRows flattens the expanded part of the tree into rows. While a filter
+// or search is active every node with a matching test is shown expanded.
+func (m *Model) treeRows() []treeRow {
+	var rows []treeRow
+	var walk func(n *treeNode, depth int) bool
+	walk = func(n *treeNode, depth int) bool {
+		if len(n.children) == 0 {
+			if n.test == nil || !m.visible(n.test) {
+				return false
+			}
+			rows = append(rows, treeRow{node: n, depth: depth})
+			return true
+		}
+
+		at := len(rows)
+		rows = append(rows, treeRow{node: n, depth: depth})
+		if !m.filtering() && !m.treeExpanded[n.id] {
+			return true
+		}
+
+		matched := false
+		for _, c := range n.children {
+			if walk(c, depth+1) {
+				matched = true
+			}
+		}
+		if !matched && m.filtering() {
+			rows = rows[:at]
+		}
+		return matched || !m.filtering()
+	}
+
+	for _, suite := range m.tree {
+		walk(suite, 0)
+	}
+	return rows
+}
+
+func (m *Model) selectedTreeNode() *treeNode {
+	rows := m.treeRows()
+	if m.treeCursor < 0 || m.treeCursor >= len(rows) {
+		return nil
+	}
+	return rows[m.treeCursor].node
+}
+
+func (m *Model) moveTreeCursor(delta int) {
+	rows := m.treeRows()
+	m.treeCursor = max(min(m.treeCursor+delta, len(rows)-1), 0)
+}
+
+func (m *Model) handleExploreKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	keys := m.keys
+
+	if m.treeSearching {
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.treeSearching = false
+		case tea.KeyEsc:
+			m.treeSearching = false
+			m.treeSearch = ""
+		case tea.KeyBackspace:
+			if r := []rune(m.treeSearch); len(r) > 0 {
+				m.treeSearch = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.treeSearch += string(msg.Runes)
+		}
+		m.treeCursor = 0
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, keys.Back), key.Matches(msg, keys.Explore):
+		m.leaveExplore()
+
+	case key.Matches(msg, keys.Up):
+		m.moveTreeCursor(-1)
+
+	case key.Matches(msg, keys.Down):
+		m.moveTreeCursor(1)
+
+	case key.Matches(msg, keys.PageUp):
+		m.moveTreeCursor(-10)
+
+	case key.Matches(msg, keys.PageDown):
+		m.moveTreeCursor(10)
+
+	case key.Matches(msg, keys.Home):
+		m.moveTreeCursor(-len(m.treeRows()))
+
+	case key.Matches(msg, keys.End):
+		m.moveTreeCursor(len(m.treeRows()))
+
+	case key.Matches(msg, keys.Enter):
+		if n := m.selectedTreeNode(); n != nil && len(n.children) > 0 {
+			m.treeExpanded[n.id] = !m.treeExpanded[n.id]
+		}
+
+	case key.Matches(msg, keys.Right):
+		if n := m.selectedTreeNode(); n != nil && len(n.children) > 0 {
+			m.treeExpanded[n.id] = true
+		}
+
+	case key.Matches(msg, keys.Left):
+		m.collapseSelected()
+
+	case key.Matches(msg, keys.Filter):
+		m.treeFilter = (m.treeFilter + 1) % (filterSlow + 1)
+		m.treeCursor = 0
+
+	case key.Matches(msg, keys.Search):
+		m.treeSearching = true
+
+	case key.Matches(msg, keys.Open):
+		return m.openInEditor()
+
+	case key.Matches(msg, keys.Rerun):
+		if n := m.selectedTreeNode(); n != nil && n.test != nil {
+			if e := m.errorForTest(n.test); e != nil {
+				return m, m.rerun(e)
+			}
+		}
+
+	case key.Matches(msg, keys.RerunAll):
+		return m, m.rerunFailed()
+	}
+
+	return m, nil
+}
+
+// collapseSelected collapses the selected node, or moves to its parent when it
+// is already collapsed.
+func (m *Model) collapseSelected() {
+	rows := m.treeRows()
+	if m.treeCursor >= len(rows) {
+		return
+	}
+	row := rows[m.treeCursor]
+	if len(row.node.children) > 0 && m.treeExpanded[row.node.id] && !m.filtering() {
+		m.treeExpanded[row.node.id] = false
+		return
+	}
+	for i := m.treeCursor - 1; i >= 0; i-- {
+		if rows[i].depth < row.depth {
+			m.treeCursor = i
+			return
+		}
+	}
+}
+
+func (m *Model) renderExplore(height int) string {
+	treeWidth := max(m.width*3/5, 30)
+	detailWidth := max(m.width-treeWidth-3, 20)
+
+	tree := m.styles.ActivePanel.Width(treeWidth).Height(height).Render(m.renderTreePanel(height, treeWidth-4))
+	details := m.styles.Panel.Width(detailWidth).Height(height).Render(m.renderDetailsPanel(detailWidth - 4))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, tree, " ", details)
+}
+
+func (m *Model) renderTreePanel(height, width int) string {
+	rows := m.treeRows()
+
+	title := m.styles.Icons.Text(fmt.Sprintf("Tests - %s", m.treeFilter))
+	if m.treeSearch != "" || m.treeSearching {
+		title += fmt.Sprintf("  /%s", m.treeSearch)
+		if m.treeSearching {
+			title += m.styles.Icons.Caret
+		}
+	}
+	lines := []string{m.styles.Bold.Render(truncateName(title, width)), ""}
+
+	if len(rows) == 0 {
+		lines = append(lines, m.styles.Dim.Render("No matching tests"))
+		return strings.Join(lines, "\n")
+	}
+
+	visibleLines := max(height-2, 1)
+	m.treeCursor = max(min(m.treeCursor, len(rows)-1), 0)
+	if m.treeCursor < m.treeOffset {
+		m.treeOffset = m.treeCursor
+	}
+	if m.treeCursor >= m.treeOffset+visibleLines {
+		m.treeOffset = m.treeCursor - visibleLines + 1
+	}
+	m.treeOffset = max(min(m.treeOffset, len(rows)-visibleLines), 0)
+
+	for i := m.treeOffset; i < min(m.treeOffset+visibleLines, len(rows)); i++ {
+		lines = append(lines, m.renderTreeRow(rows[i], width, i == m.treeCursor))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) renderTreeRow(row treeRow, width int, selected bool) string {
+	n := row.node
+	s := n.stats()
+
+	icon := " "
+	if len(n.children) > 0 {
+		icon = m.styles.Icons.Collapse
+		if m.treeExpanded[n.id] || m.filtering() {
+			icon = m.styles.Icons.Expand
+		}
+	}
+
+	status, style := m.statusIcon(s)
+	duration := formatDuration(s.duration)
+	if len(n.children) > 0 {
+		duration = fmt.Sprintf("%d tests  %s", s.tests, duration)
+	}
+
+	indent := strings.Repeat("  ", row.depth)
+	nameWidth := max(width-len(indent)-len(duration)-5, 10)
+	name := truncateName(n.label, nameWidth)
+	padding := max(width-len(indent)-4-len(name)-len(duration), 1)
+
+	line := fmt.Sprintf("%s%s %s %s%s%s", indent, icon, style.Render(status), name, strings.Repeat(" ", padding), m.styles.Dim.Render(duration))
+	if selected {
+		line = m.styles.Cursor.Render(line)
+	}
+	return line
+}
+
+func (m *Model) statusIcon(s treeStats) (string, lipgloss.Style) {
+	switch {
+	case s.failed > 0:
+		return m.styles.Icons.Failed, m.styles.TestFailed
+	case s.tests > 0 && s.skipped == s.tests:
+		return m.styles.Icons.Skipped, m.styles.TestSkipped
+	}
+	return m.styles.Icons.Passed, m.styles.TestPassed
+}
+
+func (m *Model) renderDetailsPanel(width int) string {
+	n := m.selectedTreeNode()
+	if n == nil {
+		return m.styles.Bold.Render("Details")
+	}
+
+	s := n.stats()
+	lines := []string{m.styles.Bold.Render("Details"), ""}
+	row := func(label, value string) {
+		lines = append(lines, m.styles.Dim.Render(label)+" "+truncateName(value, max(width-len(label)-1, 10)))
+	}
+
+	t := n.test
+	if t == nil || len(n.children) > 0 {
+		row(n.kind.String(), n.label)
+		row("Tests:", fmt.Sprintf("%d", s.tests))
+		if s.failed > 0 {
+			row("Failed:", fmt.Sprintf("%d", s.failed))
+		}
+		if s.skipped > 0 {
+			row("Skipped:", fmt.Sprintf("%d", s.skipped))
+		}
+		row("Duration:", formatDuration(s.duration))
+		return strings.Join(lines, "\n")
+	}
+
+	status := "passed"
+	switch t.Status {
+	case StatusFailed:
+		status = "failed"
+	case StatusSkipped:
+		status = "skipped"
+	case StatusRunning:
+		status = "did not finish"
+	}
+
+	for _, l := range wrapText(strings.TrimPrefix(t.Name, `\`), width) {
+		lines = append(lines, l)
+	}
+	lines = append(lines, "")
+	row("Status:", status)
+	row("Duration:", formatDuration(t.Duration))
+	row("Worker:", fmt.Sprintf("%d", t.WorkerID+1))
+	if t.Suite != "" {
+		row("Suite:", t.Suite)
+	}
+	if t.File != "" {
+		row("File:", t.File)
+	}
+
+	if t.ErrorMessage != "" {
+		lines = append(lines, "")
+		for _, l := range wrapText(t.ErrorMessage, width) {
+			lines = append(lines, m.styles.ErrorMsg.Render(l))
+		}
+	}
+	if t.ErrorDetails != "" {
+		for _, d := range strings.Split(t.ErrorDetails, "\n") {
+			if d != "" {
+				lines = append(lines, m.hyperlink(d, m.styles.ErrorDetail.Render(truncateName(d, width))))
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) handleHelpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.showHelp = false
+	if key.Matches(msg, m.keys.Help) || key.Matches(msg, m.keys.Back) {
+		return m, nil
+	}
+	return m.handleKeyPress(msg)
+}
+
+// renderHelp lists every key binding, in columns, using the help bubble.
+func (m *Model) renderHelp(height int) string {
+	h := help.New()
+	h.ShowAll = true
+	h.Width = max(m.width-6, 20)
+	h.FullSeparator = "    "
+	h.Styles.FullKey = m.styles.Bold
+	h.Styles.FullDesc = m.styles.Dim
+	h.Styles.FullSeparator = m.styles.Dim
+	h.Styles.Ellipsis = m.styles.Dim
+
+	lines := []string{
+		m.styles.Bold.Render("Key bindings"),
+		"",
+		m.styles.Icons.Text(h.View(m.keys)),
+		"",
+		m.styles.Dim.Render("Click a panel to focus it, click an error to expand it and scroll to move the cursor."),
+	}
+	return m.styles.ActivePanel.Width(m.width - 2).Height(height).Render(strings.Join(lines, "\n"))
+}
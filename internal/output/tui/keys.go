@@ -1,6 +1,13 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/config"
+)
 
 type KeyMap struct {
 	Up       key.Binding
@@ -11,6 +18,23 @@ type KeyMap struct {
 	PageUp   key.Binding
 	PageDown key.Binding
 	Copy     key.Binding
+	Stop     key.Binding
+	Left     key.Binding
+	Right    key.Binding
+	Explore  key.Binding
+	Filter   key.Binding
+	Search   key.Binding
+	Back     key.Binding
+	Group    key.Binding
+	Next     key.Binding
+	Previous key.Binding
+	Open     key.Binding
+	Rerun    key.Binding
+	RerunAll key.Binding
+	Timeline key.Binding
+	Home     key.Binding
+	End      key.Binding
+	Help     key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -32,7 +56,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("Tab", "switch panel"),
 		),
 		Quit: key.NewBinding(
-			key.WithKeys("q", "ctrl+c"),
+			key.WithKeys("q"),
 			key.WithHelp("q", "quit"),
 		),
 		PageUp: key.NewBinding(
@@ -47,5 +71,265 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("c"),
 			key.WithHelp("c", "copy error"),
 		),
+		Stop: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "stop after current tests"),
+		),
+		Left: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("←/h", "collapse"),
+		),
+		Right: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("→/l", "expand"),
+		),
+		Explore: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "explore tests"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "filter by status"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("Esc", "back"),
+		),
+		Group: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "group by message"),
+		),
+		Next: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		Previous: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
+		Open: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "open in editor"),
+		),
+		Rerun: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "re-run test"),
+		),
+		RerunAll: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "re-run failed tests"),
+		),
+		Timeline: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "timeline"),
+		),
+		Home: key.NewBinding(
+			key.WithKeys("home"),
+			key.WithHelp("Home", "first"),
+		),
+		End: key.NewBinding(
+			key.WithKeys("end"),
+			key.WithHelp("End", "last"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+	}
+}
+
+// VimKeyMap adds Vim's paging and G keys to the defaults, which already move
+// with hjkl.
+func VimKeyMap() KeyMap {
+	k := DefaultKeyMap()
+	rebind(&k.PageUp, "pgup", "ctrl+b", "ctrl+u")
+	rebind(&k.PageDown, "pgdown", "ctrl+f", "ctrl+d")
+	rebind(&k.End, "end", "G")
+	return k
+}
+
+// EmacsKeyMap moves with Emacs' control keys in place of hjkl.
+func EmacsKeyMap() KeyMap {
+	k := DefaultKeyMap()
+	rebind(&k.Up, "up", "ctrl+p")
+	rebind(&k.Down, "down", "ctrl+n")
+	rebind(&k.Left, "left", "ctrl+b")
+	rebind(&k.Right, "right", "ctrl+f")
+	rebind(&k.PageUp, "pgup", "alt+v")
+	rebind(&k.PageDown, "pgdown", "ctrl+v")
+	rebind(&k.Home, "home", "alt+<")
+	rebind(&k.End, "end", "alt+>")
+	rebind(&k.Back, "esc", "ctrl+g")
+	rebind(&k.Search, "/", "ctrl+s")
+	return k
+}
+
+// KeyMapFor returns the bindings of a preset with the configured overrides.
+// Binding an action to no keys disables it.
+func KeyMapFor(cfg config.Keys) (KeyMap, error) {
+	var k KeyMap
+	switch cfg.Preset {
+	case "", "default":
+		k = DefaultKeyMap()
+	case "vim":
+		k = VimKeyMap()
+	case "emacs":
+		k = EmacsKeyMap()
+	default:
+		return KeyMap{}, fmt.Errorf("unknown key preset %q, expected default, vim or emacs", cfg.Preset)
+	}
+
+	actions := k.actions()
+	for _, b := range cfg.Bindings {
+		binding, ok := actions[b.Action]
+		if !ok {
+			return KeyMap{}, fmt.Errorf("unknown key binding action %q", b.Action)
+		}
+		var keys []string
+		for _, name := range strings.Split(b.Keys, ",") {
+			name = strings.TrimSpace(name)
+			switch name {
+			case "":
+				continue
+			case "space":
+				name = " "
+			case "comma":
+				name = ","
+			case "ctrl+c":
+				return KeyMap{}, fmt.Errorf("ctrl+c can't be bound to %q, it always stops or quits", b.Action)
+			}
+			keys = append(keys, name)
+		}
+		rebind(binding, keys...)
+	}
+	return k, nil
+}
+
+// actions returns the bindings by the action names used in the config.
+func (k *KeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":        &k.Up,
+		"down":      &k.Down,
+		"left":      &k.Left,
+		"right":     &k.Right,
+		"page-up":   &k.PageUp,
+		"page-down": &k.PageDown,
+		"home":      &k.Home,
+		"end":       &k.End,
+		"enter":     &k.Enter,
+		"tab":       &k.Tab,
+		"back":      &k.Back,
+		"quit":      &k.Quit,
+		"copy":      &k.Copy,
+		"stop":      &k.Stop,
+		"explore":   &k.Explore,
+		"filter":    &k.Filter,
+		"search":    &k.Search,
+		"group":     &k.Group,
+		"next":      &k.Next,
+		"previous":  &k.Previous,
+		"open":      &k.Open,
+		"rerun":     &k.Rerun,
+		"rerun-all": &k.RerunAll,
+		"timeline":  &k.Timeline,
+		"help":      &k.Help,
+	}
+}
+
+// ShortHelp and FullHelp let the key map be shown by the help bubble.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Tab, k.Up, k.Down, k.Enter, k.Help, k.Quit}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Left, k.Right, k.PageUp, k.PageDown, k.Home, k.End},
+		{k.Tab, k.Enter, k.Back, k.Search, k.Next, k.Previous, k.Group, k.Filter},
+		{k.Copy, k.Open, k.Rerun, k.RerunAll, k.Stop, k.Explore, k.Timeline},
+		{k.Help, k.Quit},
+	}
+}
+
+func rebind(b *key.Binding, keys ...string) {
+	b.SetKeys(keys...)
+	b.SetEnabled(len(keys) > 0)
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = keyLabel(k)
+	}
+	b.SetHelp(strings.Join(labels, "/"), b.Help().Desc)
+}
+
+var keyLabels = map[string]string{
+	"up":     "↑",
+	"down":   "↓",
+	"left":   "←",
+	"right":  "→",
+	"enter":  "Enter",
+	"tab":    "Tab",
+	"esc":    "Esc",
+	"pgup":   "PgUp",
+	"pgdown": "PgDn",
+	"home":   "Home",
+	"end":    "End",
+	" ":      "Space",
+}
+
+// keyLabel returns how a key is written in help text, such as Ctrl+B for
+// ctrl+b.
+func keyLabel(k string) string {
+	if label, ok := keyLabels[k]; ok {
+		return label
+	}
+	mod, rest, ok := strings.Cut(k, "+")
+	if !ok || rest == "" {
+		return k
+	}
+	switch label, known := keyLabels[rest]; {
+	case known:
+		rest = label
+	case len(rest) == 1:
+		rest = strings.ToUpper(rest)
+	default:
+		rest = strings.ToUpper(rest[:1]) + rest[1:]
+	}
+	return strings.ToUpper(mod[:1]) + mod[1:] + "+" + rest
+}
+
+// hint describes an action in the help bar by the first key of each binding,
+// leaving out disabled bindings.
+func hint(desc string, bindings ...key.Binding) string {
+	var labels []string
+	arrows := true
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		label := keyLabel(b.Keys()[0])
+		arrows = arrows && strings.ContainsAny(label, "↑↓←→") && len([]rune(label)) == 1
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	sep := "/"
+	if arrows {
+		sep = ""
+	}
+	return "[" + strings.Join(labels, sep) + "] " + desc
+}
+
+// hints joins the hints of the help bar, skipping the empty ones.
+func hints(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
 	}
+	return strings.Join(out, "  ")
 }
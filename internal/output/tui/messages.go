@@ -4,6 +4,9 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
 )
 
 type WorkerStartMsg struct {
@@ -11,22 +14,44 @@ type WorkerStartMsg struct {
 	TestCount int
 }
 
+type WorkerHookMsg struct {
+	WorkerID int
+	Result   output.HookResult
+}
+
+type WorkerBatchMsg struct {
+	WorkerID  int
+	FileCount int
+}
+
+// FileDoneMsg reports that every test of a worker's file has finished.
+type FileDoneMsg struct {
+	WorkerID int
+	File     string
+}
+
 type TestStartMsg struct {
 	WorkerID    int
 	TestKey     string
 	DisplayName string
+	File        string
+	Suite       string
 }
 
 type TestPassMsg struct {
 	WorkerID int
 	TestName string
+	Duration time.Duration
 }
 
 type TestFailMsg struct {
-	WorkerID int
-	TestName string
-	Message  string
-	Details  string
+	WorkerID   int
+	TestName   string
+	Message    string
+	Details    string
+	Expected   string
+	Actual     string
+	Comparison bool
 }
 
 type TestSkipMsg struct {
@@ -40,6 +65,11 @@ type TestCountMsg struct {
 	Count    int
 }
 
+type WorkerUsageMsg struct {
+	WorkerID int
+	Usage    output.ResourceUsage
+}
+
 type WorkerCompleteMsg struct {
 	WorkerID int
 	Error    error
@@ -50,10 +80,35 @@ type CleanupProgressMsg struct {
 	Total     int
 }
 
-type FinishMsg struct{}
+type DrainingMsg struct{}
+
+type FinishMsg struct {
+	Timeline timeline.Timeline
+}
 
 type TickMsg struct{}
 
+type WorkerProcessMsg struct {
+	WorkerID int
+	Process  output.ProcessInfo
+}
+
+type WorkerOutputMsg struct {
+	WorkerID int
+	Line     string
+}
+
+type RerunResultMsg struct {
+	Index    int
+	Previous TestStatus
+	Result   output.RerunResult
+	Err      error
+}
+
+type EditorClosedMsg struct {
+	Err error
+}
+
 type CopyNoticeExpiredMsg struct{}
 
 func tick() tea.Cmd {
@@ -1,9 +1,12 @@
 package tui
 
 import (
+	"os"
+	"regexp"
 	"time"
 
 	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
 )
 
 type TestStatus int
@@ -23,6 +26,9 @@ type TestNode struct {
 	ErrorMessage string
 	ErrorDetails string
 	WorkerID     int
+	File         string
+	Suite        string
+	Duration     time.Duration
 }
 
 type WorkerNode struct {
@@ -33,14 +39,51 @@ type WorkerNode struct {
 	Failed       int
 	HasTestCount bool
 	TestFiles    int
+	HookTime     time.Duration
+	BatchFiles   int
+	CountedFiles int
+	CountedTests int
+	Memory       uint64
+	PeakMemory   uint64
+	CPUTime      time.Duration
+	Files        []string
+	DoneFiles    map[string]bool
+	FileStarted  time.Time
+	CurrentFile  string
+	Process      output.ProcessInfo
+	Hooks        []output.HookResult
+	Output       []string
+	Finished     bool
+	Err          error
+}
+
+// current returns the most recently started test with the given key. Keys are
+// only unique among the tests a worker is running at once.
+func (w *WorkerNode) current(key string) *TestNode {
+	for i := len(w.Tests) - 1; i >= 0; i-- {
+		if w.Tests[i].Key == key {
+			return w.Tests[i]
+		}
+	}
+	return nil
 }
 
 type ErrorEntry struct {
-	TestName string
-	Message  string
-	Details  string
-	WorkerID int
-	Expanded bool
+	TestName    string
+	Message     string
+	Details     string
+	WorkerID    int
+	Expanded    bool
+	Flaky       bool
+	Quarantined bool
+	Comparison  bool
+	// Diff is computed once when the failure arrives, as rendering runs on
+	// every frame.
+	Diff []output.DiffLine
+	// Rerun is the status of the latest re-run, or StatusPending when the test
+	// hasn't been re-run.
+	Rerun TestStatus
+	test  *TestNode
 }
 
 type RunPhase int
@@ -82,39 +125,94 @@ type Model struct {
 	totalComplete    int
 	totalFailed      int
 	totalSkipped     int
+	hookTime         time.Duration
+	hookErrors       int
 	copyNotice       string
+	editor           string
+	styles           Styles
+	keys             KeyMap
+	showHelp         bool
+	panelAreas       map[Panel]area
+	errorLines       []errorLine
+	workerCursor     int
+	inspecting       bool
+	inspectOffset    int
+	timeline         timeline.Timeline
+	viewingTimeline  bool
+	timelineWorker   int
+	timelineColumn   int
+	onRerun          func(output.RerunRequest) (output.RerunResult, error)
+	cwd              string
 	cleanupCompleted int
 	cleanupTotal     int
 	filter           string
 	group            string
 	excludeGroup     string
+	phpunitArgs      []string
+	seed             int64
+	draining         bool
+	filePeaks        map[string]uint64
+	fileTimes        map[string]time.Duration
+	averageFileTime  time.Duration
+	knownFlaky       output.TestSet
+	quarantine       output.TestSet
+	totalQuarantined int
+	tree             []*treeNode
+	treeExpanded     map[string]bool
+	treeCursor       int
+	treeOffset       int
+	treeFilter       treeFilter
+	treeSearch       string
+	treeSearching    bool
+	errorSearch      string
+	errorSearching   bool
+	errorRegex       bool
+	errorPattern     *regexp.Regexp
+	errorPatternErr  error
+	groupErrors      bool
+	expandedGroups   map[string]bool
+	onInterrupt      func()
 }
 
 func NewModel(opts output.StartOptions) *Model {
 	m := &Model{
-		workers:      make(map[int]*WorkerNode),
-		workerOrder:  make([]int, 0, opts.WorkerCount),
-		errors:       make([]ErrorEntry, 0),
-		phase:        PhaseRunning,
-		activePanel:  PanelErrors,
-		testCount:    opts.TestCount,
-		workerCount:  opts.WorkerCount,
-		startTime:    time.Now(),
-		width:        80,
-		height:       24,
-		filter:       opts.Filter,
-		group:        opts.Group,
-		excludeGroup: opts.ExcludeGroup,
+		workers:        make(map[int]*WorkerNode),
+		workerOrder:    make([]int, 0, opts.WorkerCount),
+		errors:         make([]ErrorEntry, 0),
+		filePeaks:      make(map[string]uint64),
+		expandedGroups: make(map[string]bool),
+		phase:          PhaseRunning,
+		activePanel:    PanelErrors,
+		testCount:      opts.TestCount,
+		workerCount:    opts.WorkerCount,
+		startTime:      time.Now(),
+		width:          80,
+		height:         24,
+		filter:         opts.Filter,
+		group:          opts.Group,
+		excludeGroup:   opts.ExcludeGroup,
+		phpunitArgs:    opts.PHPUnitArgs,
+		seed:           opts.Seed,
+		knownFlaky:     output.NewTestSet(opts.KnownFlaky),
+		quarantine:     output.NewTestSet(opts.Quarantine),
+		editor:         opts.Editor,
+		styles:         DefaultStyles(),
+		keys:           DefaultKeyMap(),
+		fileTimes:      opts.FileTimes,
 	}
+	m.cwd, _ = os.Getwd()
 
 	for i := range opts.WorkerCount {
 		m.workers[i] = &WorkerNode{
 			ID:        i,
 			Tests:     make([]*TestNode, 0),
 			TestFiles: 0,
+			Files:     opts.WorkerFiles[i],
+			DoneFiles: make(map[string]bool),
 		}
 		m.workerOrder = append(m.workerOrder, i)
 	}
+	m.averageFileTime = averageFileTime(opts.WorkerFiles, opts.FileTimes)
 
 	return m
 }
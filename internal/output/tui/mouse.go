@@ -0,0 +1,75 @@
+package tui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// area is the screen rectangle a panel was last drawn in.
+type area struct {
+	x, y          int
+	width, height int
+}
+
+func (a area) contains(x, y int) bool {
+	return x >= a.x && x < a.x+a.width && y >= a.y && y < a.y+a.height
+}
+
+// errorLine records which error row a line of the errors panel belongs to,
+// and whether it is the row's name rather than its details.
+type errorLine struct {
+	row  int
+	head bool
+}
+
+// handleMouse focuses the panel under a click and moves its cursor with the
+// wheel. Clicking an error's name expands or collapses it.
+func (m *Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress || m.showHelp || m.errorSearching {
+		return m, nil
+	}
+
+	var delta int
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		delta = -1
+	case tea.MouseButtonWheelDown:
+		delta = 1
+	case tea.MouseButtonLeft:
+	default:
+		return m, nil
+	}
+
+	switch {
+	case m.phase == PhaseExploring:
+		m.moveTreeCursor(delta)
+		return m, nil
+	case m.inspecting:
+		m.inspectOffset = max(m.inspectOffset-delta, 0)
+		return m, nil
+	case m.viewingTimeline:
+		m.timelineWorker = max(min(m.timelineWorker+delta, len(m.timeline.Workers)-1), 0)
+		return m, nil
+	}
+
+	for panel, a := range m.panelAreas {
+		if !a.contains(msg.X, msg.Y) {
+			continue
+		}
+		m.activePanel = panel
+		if delta != 0 {
+			m.moveCursor(delta)
+		} else if panel == PanelErrors {
+			// Skip the panel's top border
+			m.clickError(msg.Y - a.y - 1)
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) clickError(line int) {
+	if line < 0 || line >= len(m.errorLines) || m.errorLines[line].row < 0 {
+		return
+	}
+	m.errorCursor = m.errorLines[line].row
+	if m.errorLines[line].head {
+		m.toggle()
+	}
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// rerun starts a fresh run of the error's test once the run has finished.
+func (m *Model) rerun(e *ErrorEntry) tea.Cmd {
+	if m.onRerun == nil || (m.phase != PhaseComplete && m.phase != PhaseExploring) {
+		return nil
+	}
+	if e.Rerun == StatusRunning {
+		return nil
+	}
+	if e.test == nil || e.test.File == "" {
+		return m.notify("Can't re-run " + e.TestName + ": its file is unknown")
+	}
+
+	index := -1
+	for i := range m.errors {
+		if &m.errors[i] == e {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	file := e.test.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(m.cwd, file)
+	}
+	req := output.RerunRequest{
+		Name:  e.TestName,
+		Key:   e.test.Key,
+		File:  file,
+		Suite: e.test.Suite,
+	}
+	previous := e.Rerun
+	e.Rerun = StatusRunning
+
+	onRerun := m.onRerun
+	return func() tea.Msg {
+		result, err := onRerun(req)
+		return RerunResultMsg{Index: index, Previous: previous, Result: result, Err: err}
+	}
+}
+
+// rerunFailed re-runs every test that is still failing. The runner limits how
+// many run at once.
+func (m *Model) rerunFailed() tea.Cmd {
+	var cmds []tea.Cmd
+	for i := range m.errors {
+		if m.errors[i].Rerun == StatusPending || m.errors[i].Rerun == StatusFailed {
+			cmds = append(cmds, m.rerun(&m.errors[i]))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// applyRerun updates the error, its test and the failure counts with the
+// result of a re-run.
+func (m *Model) applyRerun(index int, result output.RerunResult) {
+	e := &m.errors[index]
+	wasFailing := e.test.Status == StatusFailed
+
+	delta := 0
+	if wasFailing && !result.Failed {
+		delta = -1
+	} else if !wasFailing && result.Failed {
+		delta = 1
+	}
+	m.totalFailed += delta
+	if w := m.workers[e.WorkerID]; w != nil {
+		w.Failed += delta
+	}
+	if e.Quarantined {
+		m.totalQuarantined += delta
+	}
+
+	t := e.test
+	t.Duration = result.Duration
+	switch {
+	case result.Failed:
+		e.Rerun = StatusFailed
+		e.Message = result.Message
+		e.Details = result.Details
+		e.Comparison = result.Comparison
+		e.Diff = comparisonDiff(result.Expected, result.Actual, result.Comparison)
+		t.Status = StatusFailed
+		t.ErrorMessage = result.Message
+		t.ErrorDetails = result.Details
+	case result.Skipped:
+		e.Rerun = StatusSkipped
+		t.Status = StatusSkipped
+		t.ErrorMessage = result.Message
+		t.ErrorDetails = ""
+	default:
+		e.Rerun = StatusPassed
+		t.Status = StatusPassed
+		t.ErrorMessage = ""
+		t.ErrorDetails = ""
+	}
+}
+
+// errorForTest returns the error entry of a test in the explore tree.
+func (m *Model) errorForTest(t *TestNode) *ErrorEntry {
+	for i := range m.errors {
+		if m.errors[i].test == t {
+			return &m.errors[i]
+		}
+	}
+	return nil
+}
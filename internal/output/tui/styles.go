@@ -1,6 +1,14 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/config"
+)
 
 type Styles struct {
 	Title       lipgloss.Style
@@ -16,71 +24,264 @@ type Styles struct {
 	ActivePanel lipgloss.Style
 	Dim         lipgloss.Style
 	Bold        lipgloss.Style
+	Badge       lipgloss.Style
+	DiffDelete  lipgloss.Style
+	DiffInsert  lipgloss.Style
+	DiffHunk    lipgloss.Style
+
+	Icons Icons
+}
+
+// Palette holds the colours of a theme as ANSI numbers or hex codes. A mono
+// palette uses no colours at all, only bold, faint and reversed text.
+type Palette struct {
+	Title         string
+	TitleText     string
+	Passed        string
+	Failed        string
+	Running       string
+	Skipped       string
+	Warning       string
+	Dim           string
+	Border        string
+	ActiveBorder  string
+	Selection     string
+	SelectionText string
+	Mono          bool
+}
 
-	IconRunning string
-	IconExpand  string
-	IconCollaps string
+var themes = map[string]Palette{
+	"dark": {
+		Title: "4", TitleText: "15", Passed: "2", Failed: "1", Running: "6", Skipped: "3", Warning: "3",
+		Dim: "8", Border: "8", ActiveBorder: "6", Selection: "8", SelectionText: "15",
+	},
+	"light": {
+		Title: "25", TitleText: "231", Passed: "28", Failed: "160", Running: "31", Skipped: "130", Warning: "130",
+		Dim: "244", Border: "250", ActiveBorder: "31", Selection: "252", SelectionText: "16",
+	},
+	"high-contrast": {
+		Title: "11", TitleText: "0", Passed: "10", Failed: "9", Running: "14", Skipped: "11", Warning: "11",
+		Dim: "7", Border: "15", ActiveBorder: "14", Selection: "15", SelectionText: "0",
+	},
+	"no-color": {Mono: true},
+}
+
+// Icons are the symbols the terminal UI draws with.
+type Icons struct {
+	Running  string
+	Expand   string
+	Collapse string
+	Passed   string
+	Failed   string
+	Skipped  string
+	Pending  string
+	BarFull  string
+	BarAlt   string
+	BarEmpty string
+	Hook     string
+	Idle     string
+	Marker   string
+	Caret    string
+	Border   lipgloss.Border
+
+	text *strings.Replacer
+}
+
+var unicodeIcons = Icons{
+	Running:  "●",
+	Expand:   "▼",
+	Collapse: "►",
+	Passed:   "✓",
+	Failed:   "✗",
+	Skipped:  "○",
+	Pending:  "○",
+	BarFull:  "█",
+	BarAlt:   "▓",
+	BarEmpty: "░",
+	Hook:     "▒",
+	Idle:     "·",
+	Marker:   "│",
+	Caret:    "█",
+	Border:   lipgloss.RoundedBorder(),
+}
+
+var asciiIcons = Icons{
+	Running:  "*",
+	Expand:   "v",
+	Collapse: ">",
+	Passed:   "+",
+	Failed:   "x",
+	Skipped:  "-",
+	Pending:  ".",
+	BarFull:  "#",
+	BarAlt:   "=",
+	BarEmpty: ".",
+	Hook:     "~",
+	Idle:     ".",
+	Marker:   "|",
+	Caret:    "_",
+	Border:   lipgloss.ASCIIBorder(),
+	text: strings.NewReplacer(
+		"↑↓", "Up/Down", "←→", "Left/Right",
+		"↑", "Up", "↓", "Down", "←", "Left", "→", "Right",
+		"≥", ">=", "…", "...", "×", "x",
+	),
+}
+
+// Text replaces the symbols in labels and help text that the icon set can't
+// draw.
+func (i Icons) Text(s string) string {
+	if i.text == nil {
+		return s
+	}
+	return i.text.Replace(s)
 }
 
 func DefaultStyles() Styles {
-	green := lipgloss.Color("2")
-	red := lipgloss.Color("1")
-	yellow := lipgloss.Color("3")
-	cyan := lipgloss.Color("6")
-	dim := lipgloss.Color("8")
-	white := lipgloss.Color("15")
-	blue := lipgloss.Color("4")
-
-	return Styles{
+	return NewStyles(themes["dark"], unicodeIcons)
+}
+
+// ThemeStyles returns the styles of a built-in theme with any colours the
+// configured theme overrides. Without a configured theme, the no-color theme
+// is used when NO_COLOR is set.
+func ThemeStyles(theme config.Theme, iconSet string) (Styles, error) {
+	name := theme.Name
+	if name == "" {
+		name = "dark"
+		if os.Getenv("NO_COLOR") != "" && theme == (config.Theme{}) {
+			name = "no-color"
+		}
+	}
+	palette, ok := themes[name]
+	if !ok {
+		return Styles{}, fmt.Errorf("unknown theme %q, expected dark, light, high-contrast or no-color", theme.Name)
+	}
+
+	for _, c := range []struct {
+		value string
+		dest  *string
+	}{
+		{theme.Title, &palette.Title},
+		{theme.TitleText, &palette.TitleText},
+		{theme.Passed, &palette.Passed},
+		{theme.Failed, &palette.Failed},
+		{theme.Running, &palette.Running},
+		{theme.Skipped, &palette.Skipped},
+		{theme.Warning, &palette.Warning},
+		{theme.Dim, &palette.Dim},
+		{theme.Border, &palette.Border},
+		{theme.ActiveBorder, &palette.ActiveBorder},
+		{theme.Selection, &palette.Selection},
+		{theme.SelectionText, &palette.SelectionText},
+	} {
+		if c.value != "" {
+			*c.dest = c.value
+			palette.Mono = false
+		}
+	}
+
+	switch iconSet {
+	case "", "unicode":
+		return NewStyles(palette, unicodeIcons), nil
+	case "ascii":
+		return NewStyles(palette, asciiIcons), nil
+	}
+	return Styles{}, fmt.Errorf("unknown icon set %q, expected unicode or ascii", iconSet)
+}
+
+func NewStyles(p Palette, icons Icons) Styles {
+	color := func(c string) lipgloss.TerminalColor {
+		if c == "" || p.Mono {
+			return lipgloss.NoColor{}
+		}
+		return lipgloss.Color(c)
+	}
+
+	s := Styles{
 		Title: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(white).
-			Background(blue).
+			Foreground(color(p.TitleText)).
+			Background(color(p.Title)).
 			Padding(0, 1),
 
 		TestPassed: lipgloss.NewStyle().
-			Foreground(green),
+			Foreground(color(p.Passed)),
 
 		TestFailed: lipgloss.NewStyle().
-			Foreground(red),
+			Foreground(color(p.Failed)),
 
 		TestRunning: lipgloss.NewStyle().
-			Foreground(cyan),
+			Foreground(color(p.Running)),
 
 		TestSkipped: lipgloss.NewStyle().
-			Foreground(yellow),
+			Foreground(color(p.Skipped)),
 
 		ErrorMsg: lipgloss.NewStyle().
-			Foreground(yellow),
+			Foreground(color(p.Warning)),
 
 		ErrorDetail: lipgloss.NewStyle().
-			Foreground(dim),
+			Foreground(color(p.Dim)),
 
 		HelpBar: lipgloss.NewStyle().
-			Foreground(dim),
+			Foreground(color(p.Dim)),
 
 		Cursor: lipgloss.NewStyle().
-			Background(lipgloss.Color("8")).
-			Foreground(white),
+			Background(color(p.Selection)).
+			Foreground(color(p.SelectionText)),
 
 		Panel: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(dim).
+			Border(icons.Border).
+			BorderForeground(color(p.Border)).
 			Padding(0, 1),
 
 		ActivePanel: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(cyan).
+			Border(icons.Border).
+			BorderForeground(color(p.ActiveBorder)).
 			Padding(0, 1),
 
 		Dim: lipgloss.NewStyle().
-			Foreground(dim),
+			Foreground(color(p.Dim)),
 
 		Bold: lipgloss.NewStyle().
 			Bold(true),
 
-		IconRunning: "●",
-		IconExpand:  "▼",
-		IconCollaps: "►",
+		Badge: lipgloss.NewStyle().
+			Foreground(color(p.Warning)),
+
+		DiffDelete: lipgloss.NewStyle().
+			Foreground(color(p.Failed)),
+
+		DiffInsert: lipgloss.NewStyle().
+			Foreground(color(p.Passed)),
+
+		DiffHunk: lipgloss.NewStyle().
+			Foreground(color(p.Running)),
+
+		Icons: icons,
 	}
+
+	if p.Mono {
+		s.Title = s.Title.Reverse(true)
+		s.Cursor = s.Cursor.Reverse(true)
+		s.TestFailed = s.TestFailed.Bold(true)
+		s.DiffDelete = s.DiffDelete.Bold(true)
+		s.ErrorMsg = s.ErrorMsg.Bold(true)
+		s.ErrorDetail = s.ErrorDetail.Faint(true)
+		s.HelpBar = s.HelpBar.Faint(true)
+		s.Dim = s.Dim.Faint(true)
+		// Without colour the active panel is told apart by a heavier border
+		if icons.Border == lipgloss.RoundedBorder() {
+			s.ActivePanel = s.ActivePanel.Border(lipgloss.ThickBorder())
+		} else {
+			s.ActivePanel = s.ActivePanel.Border(asciiActiveBorder)
+		}
+	}
+
+	return s
+}
+
+// asciiActiveBorder marks the active panel with = rather than colour.
+var asciiActiveBorder = lipgloss.Border{
+	Top: "=", Bottom: "=", Left: "|", Right: "|",
+	TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
 }
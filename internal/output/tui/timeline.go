@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
+)
+
+// timelineLabelWidth is the width of the worker labels left of the bars.
+const timelineLabelWidth = 10
+
+func (m *Model) handleTimelineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	keys := m.keys
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, keys.Back), key.Matches(msg, keys.Timeline):
+		m.viewingTimeline = false
+
+	case key.Matches(msg, keys.Up):
+		m.timelineWorker = max(m.timelineWorker-1, 0)
+
+	case key.Matches(msg, keys.Down):
+		m.timelineWorker = min(m.timelineWorker+1, len(m.timeline.Workers)-1)
+
+	case key.Matches(msg, keys.Left):
+		m.timelineColumn--
+
+	case key.Matches(msg, keys.Right):
+		m.timelineColumn++
+
+	case key.Matches(msg, keys.PageUp):
+		m.timelineColumn -= 10
+
+	case key.Matches(msg, keys.PageDown):
+		m.timelineColumn += 10
+	}
+
+	return m, nil
+}
+
+func (m *Model) renderTimeline(height int) string {
+	width := max(m.width-4, 40)
+	barWidth := max(width-timelineLabelWidth-12, 10)
+	total := max(m.timeline.Duration, time.Millisecond)
+	step := total / time.Duration(barWidth)
+	m.timelineColumn = max(min(m.timelineColumn, barWidth-1), 0)
+
+	lines := []string{
+		m.styles.Bold.Render(fmt.Sprintf("Timeline (%s)", formatDuration(m.timeline.Duration))),
+		"",
+		strings.Repeat(" ", timelineLabelWidth) + m.renderTimelineAxis(barWidth, total),
+	}
+
+	visible := max(height-8, 1)
+	offset := max(m.timelineWorker-visible+1, 0)
+	for i := offset; i < min(offset+visible, len(m.timeline.Workers)); i++ {
+		w := m.timeline.Workers[i]
+		label := fmt.Sprintf("Worker %d", w.ID+1)
+		if i == m.timelineWorker {
+			label = m.styles.Cursor.Render(label)
+		}
+		idle := 0
+		if span := w.End - w.Start; span > 0 {
+			idle = int(w.Idle() * 100 / span)
+		}
+		lines = append(lines, label+strings.Repeat(" ", max(timelineLabelWidth-len(fmt.Sprintf("Worker %d", w.ID+1)), 1))+
+			m.renderTimelineBar(w, barWidth, step, i == m.timelineWorker)+
+			m.styles.Dim.Render(fmt.Sprintf(" %3d%% idle", idle)))
+	}
+
+	lines = append(lines, "",
+		m.styles.TestPassed.Render(m.styles.Icons.BarFull+m.styles.Icons.BarAlt)+m.styles.Dim.Render(" test files  ")+
+			m.styles.Badge.Render(m.styles.Icons.Hook)+m.styles.Dim.Render(" hooks  ")+
+			m.styles.TestFailed.Render(m.styles.Icons.BarFull)+m.styles.Dim.Render(" failures  ")+
+			m.styles.Dim.Render(m.styles.Icons.Idle+" idle"),
+	)
+
+	if len(m.timeline.Workers) > 0 {
+		w := m.timeline.Workers[m.timelineWorker]
+		at := step*time.Duration(m.timelineColumn) + step/2
+		detail := fmt.Sprintf("Worker %d at %s: ", w.ID+1, formatDuration(at))
+		if s, ok := w.At(at); ok {
+			detail += fmt.Sprintf("%s (%s, %s - %s)", s.Label, formatDuration(s.Duration()), formatDuration(s.Start), formatDuration(s.End))
+		} else {
+			detail += "idle"
+		}
+		lines = append(lines, truncateName(detail, width))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) renderTimelineAxis(width int, total time.Duration) string {
+	axis := []rune(strings.Repeat(" ", width))
+	for col := 0; col < width; col += 20 {
+		label := formatDuration(total * time.Duration(col) / time.Duration(width))
+		copy(axis[col:], []rune(label))
+	}
+	return m.styles.Dim.Render(string(axis))
+}
+
+// renderTimelineBar draws each column of the bar as the span that covers
+// most of its time.
+func (m *Model) renderTimelineBar(w timeline.Worker, width int, step time.Duration, selected bool) string {
+	var b strings.Builder
+	for col := range width {
+		start := step * time.Duration(col)
+		end := start + step
+
+		best := -1
+		var covered time.Duration
+		for i, s := range w.Spans {
+			overlap := min(s.End, end) - max(s.Start, start)
+			if overlap > covered {
+				best, covered = i, overlap
+			}
+		}
+
+		var cell string
+		switch {
+		case best >= 0:
+			s := w.Spans[best]
+			cell = m.styles.TestPassed.Render([]string{m.styles.Icons.BarFull, m.styles.Icons.BarAlt}[fileIndex(w, best)%2])
+			if s.Kind == timeline.KindHook {
+				cell = m.styles.Badge.Render(m.styles.Icons.Hook)
+			}
+			if s.Failed {
+				cell = m.styles.TestFailed.Render(m.styles.Icons.BarFull)
+			}
+		case end > w.Start && start < w.End:
+			cell = m.styles.Dim.Render(m.styles.Icons.Idle)
+		default:
+			cell = " "
+		}
+		if selected && col == m.timelineColumn {
+			cell = m.styles.Cursor.Render(m.styles.Icons.Marker)
+		}
+		b.WriteString(cell)
+	}
+	return b.String()
+}
+
+// fileIndex returns how many test files the worker ran before span i, so that
+// neighbouring files can be told apart.
+func fileIndex(w timeline.Worker, i int) int {
+	n := 0
+	for _, s := range w.Spans[:i] {
+		if s.Kind == timeline.KindFile {
+			n++
+		}
+	}
+	return n
+}
@@ -3,12 +3,16 @@ package tui
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
 )
 
 type TUIOutput struct {
@@ -16,16 +20,41 @@ type TUIOutput struct {
 	model    *Model
 	mu       sync.Mutex
 	onCancel func()
+	onRerun  func(output.RerunRequest) (output.RerunResult, error)
 	stopped  bool
+	suites   map[int]string
+	// files maps the names of the running file-level suites of each worker to
+	// their files, as testSuiteFinished carries no location
+	files    map[int]map[string]string
+	cwd      string
+	baseDir  string
+	timeline timeline.Timeline
+	styles   Styles
+	keys     KeyMap
 }
 
-func New() *TUIOutput {
-	return &TUIOutput{}
+func New(s Styles, keys KeyMap) *TUIOutput {
+	cwd, _ := os.Getwd()
+	return &TUIOutput{
+		suites: make(map[int]string),
+		files:  make(map[int]map[string]string),
+		cwd:    cwd,
+		styles: s,
+		keys:   keys,
+	}
 }
 
 func (t *TUIOutput) Start(opts output.StartOptions) {
 	t.model = NewModel(opts)
-	t.program = tea.NewProgram(t.model, tea.WithAltScreen())
+	t.baseDir = opts.BaseDir
+	if t.baseDir == "" {
+		t.baseDir = t.cwd
+	}
+	t.model.onInterrupt = t.onCancel
+	t.model.onRerun = t.onRerun
+	t.model.styles = t.styles
+	t.model.keys = t.keys
+	t.program = tea.NewProgram(t.model, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithoutSignalHandler())
 
 	go func() {
 		_, _ = t.program.Run()
@@ -36,7 +65,6 @@ func (t *TUIOutput) Start(opts output.StartOptions) {
 			if t.onCancel != nil {
 				t.onCancel()
 			}
-			os.Exit(130)
 		}
 	}()
 }
@@ -53,6 +81,51 @@ func (t *TUIOutput) WorkerStart(workerID, testCount int) {
 	}
 }
 
+func (t *TUIOutput) WorkerHook(workerID int, result output.HookResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.program != nil {
+		t.program.Send(WorkerHookMsg{
+			WorkerID: workerID,
+			Result:   result,
+		})
+	}
+}
+
+func (t *TUIOutput) WorkerBatch(workerID, fileCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.program != nil {
+		t.program.Send(WorkerBatchMsg{
+			WorkerID:  workerID,
+			FileCount: fileCount,
+		})
+	}
+}
+
+func (t *TUIOutput) WorkerProcess(workerID int, process output.ProcessInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.program != nil {
+		t.program.Send(WorkerProcessMsg{
+			WorkerID: workerID,
+			Process:  process,
+		})
+	}
+}
+
+// relative returns file relative to the working directory when it is inside
+// it.
+func (t *TUIOutput) relative(file string) string {
+	if rel, err := filepath.Rel(t.cwd, file); err == nil && file != "" && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
+
 func (t *TUIOutput) WorkerLine(workerID int, line string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -61,6 +134,11 @@ func (t *TUIOutput) WorkerLine(workerID int, line string) {
 		return
 	}
 
+	t.program.Send(WorkerOutputMsg{
+		WorkerID: workerID,
+		Line:     line,
+	})
+
 	switch {
 	case strings.HasPrefix(line, "##teamcity[testCount "):
 		count := output.ParseTeamCityCount(line)
@@ -71,22 +149,57 @@ func (t *TUIOutput) WorkerLine(workerID int, line string) {
 			})
 		}
 
+	case strings.HasPrefix(line, "##teamcity[testSuiteStarted "):
+		// Configured test suites are the only suites without a location
+		name := output.ParseTeamCityAttr(line, "name")
+		if output.ParseTeamCityAttr(line, "locationHint") == "" && !strings.HasSuffix(name, ".xml") {
+			t.suites[workerID] = name
+		}
+		// File-level suites are located by file and class alone
+		if file, class := output.ParseTeamCityLocation(line); file != "" && !strings.Contains(class, "::") {
+			if t.files[workerID] == nil {
+				t.files[workerID] = make(map[string]string)
+			}
+			if rel, err := filepath.Rel(t.baseDir, file); err == nil {
+				file = rel
+			}
+			t.files[workerID][name] = file
+		}
+
+	case strings.HasPrefix(line, "##teamcity[testSuiteFinished "):
+		name := output.ParseTeamCityAttr(line, "name")
+		if file, ok := t.files[workerID][name]; ok {
+			delete(t.files[workerID], name)
+			t.program.Send(FileDoneMsg{
+				WorkerID: workerID,
+				File:     file,
+			})
+		}
+
 	case strings.HasPrefix(line, "##teamcity[testStarted "):
 		key := output.ParseTeamCityAttr(line, "name")
 		displayName := output.ParseTeamCityTestName(line)
+		file, _ := output.ParseTeamCityLocation(line)
+		file = t.relative(file)
 		t.program.Send(TestStartMsg{
 			WorkerID:    workerID,
 			TestKey:     key,
 			DisplayName: displayName,
+			File:        file,
+			Suite:       t.suites[workerID],
 		})
 
 	case strings.HasPrefix(line, "##teamcity[testFailed "):
 		name, message, details := output.ParseTeamCityError(line)
+		expected, actual, comparison := output.ParseTeamCityComparison(line)
 		t.program.Send(TestFailMsg{
-			WorkerID: workerID,
-			TestName: name,
-			Message:  message,
-			Details:  details,
+			WorkerID:   workerID,
+			TestName:   name,
+			Message:    message,
+			Details:    details,
+			Expected:   expected,
+			Actual:     actual,
+			Comparison: comparison,
 		})
 
 	case strings.HasPrefix(line, "##teamcity[testIgnored "):
@@ -100,9 +213,23 @@ func (t *TUIOutput) WorkerLine(workerID int, line string) {
 
 	case strings.HasPrefix(line, "##teamcity[testFinished "):
 		name := output.ParseTeamCityAttr(line, "name")
+		ms, _ := strconv.Atoi(output.ParseTeamCityAttr(line, "duration"))
 		t.program.Send(TestPassMsg{
 			WorkerID: workerID,
 			TestName: name,
+			Duration: time.Duration(ms) * time.Millisecond,
+		})
+	}
+}
+
+func (t *TUIOutput) WorkerUsage(workerID int, usage output.ResourceUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.program != nil {
+		t.program.Send(WorkerUsageMsg{
+			WorkerID: workerID,
+			Usage:    usage,
 		})
 	}
 }
@@ -143,10 +270,41 @@ func (t *TUIOutput) SetOnCancel(fn func()) {
 	t.onCancel = fn
 }
 
+func (t *TUIOutput) SetOnRerun(fn func(output.RerunRequest) (output.RerunResult, error)) {
+	t.onRerun = fn
+}
+
+func (t *TUIOutput) Draining() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.program != nil && !t.stopped {
+		t.program.Send(DrainingMsg{})
+	}
+}
+
+func (t *TUIOutput) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.program == nil {
+		return
+	}
+	t.stopped = true
+	t.program.Quit()
+}
+
+// SetTimeline sets the timeline shown once the run has finished.
+func (t *TUIOutput) SetTimeline(tl timeline.Timeline) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeline = tl
+}
+
 func (t *TUIOutput) Finish() {
 	t.mu.Lock()
 	if t.program != nil {
-		t.program.Send(FinishMsg{})
+		t.program.Send(FinishMsg{Timeline: t.timeline})
 	}
 	t.mu.Unlock()
 
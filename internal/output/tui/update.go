@@ -8,6 +8,8 @@ import (
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
 )
 
 const tickInterval = 100 * time.Millisecond
@@ -21,6 +23,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -36,6 +41,52 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.handleWorkerStart(msg)
 		return m, nil
 
+	case WorkerHookMsg:
+		m.handleWorkerHook(msg)
+		return m, nil
+
+	case FileDoneMsg:
+		if w, ok := m.workers[msg.WorkerID]; ok {
+			w.DoneFiles[msg.File] = true
+		}
+		return m, nil
+
+	case WorkerBatchMsg:
+		if w, ok := m.workers[msg.WorkerID]; ok {
+			w.BatchFiles = msg.FileCount
+		}
+		return m, nil
+
+	case WorkerUsageMsg:
+		m.handleWorkerUsage(msg)
+		return m, nil
+
+	case WorkerProcessMsg:
+		if w, ok := m.workers[msg.WorkerID]; ok {
+			w.Process = msg.Process
+		}
+		return m, nil
+
+	case WorkerOutputMsg:
+		if w, ok := m.workers[msg.WorkerID]; ok {
+			w.Output = append(w.Output, msg.Line)
+			if len(w.Output) > maxWorkerOutput {
+				w.Output = w.Output[len(w.Output)-maxWorkerOutput:]
+			}
+		}
+		return m, nil
+
+	case WorkerCompleteMsg:
+		if w, ok := m.workers[msg.WorkerID]; ok {
+			w.Finished = true
+			w.Err = msg.Error
+		}
+		return m, nil
+
+	case DrainingMsg:
+		m.draining = true
+		return m, nil
+
 	case TestStartMsg:
 		m.handleTestStart(msg)
 		return m, nil
@@ -65,11 +116,26 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.cleanupTotal = msg.Total
 		return m, tick()
 
+	case RerunResultMsg:
+		if msg.Err != nil {
+			m.errors[msg.Index].Rerun = msg.Previous
+			return m, m.notify(fmt.Sprintf("Re-run failed: %s", msg.Err))
+		}
+		m.applyRerun(msg.Index, msg.Result)
+		return m, nil
+
+	case EditorClosedMsg:
+		if msg.Err != nil {
+			return m, m.notify(fmt.Sprintf("Editor failed: %s", msg.Err))
+		}
+		return m, nil
+
 	case CopyNoticeExpiredMsg:
 		m.copyNotice = ""
 		return m, nil
 
 	case FinishMsg:
+		m.timeline = msg.Timeline
 		m.phase = PhaseComplete
 		if m.endTime.IsZero() {
 			m.endTime = time.Now()
@@ -82,22 +148,77 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	keys := DefaultKeyMap()
+	keys := m.keys
+
+	// Ctrl+C is handled before the key map so that it works whatever keys
+	// are bound, even while typing a search
+	if msg.String() == "ctrl+c" {
+		if m.phase == PhaseRunning && !m.draining && m.onInterrupt != nil {
+			m.drain()
+			return m, nil
+		}
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if m.showHelp {
+		return m.handleHelpKey(msg)
+	}
+	if key.Matches(msg, keys.Help) && !m.treeSearching && !m.errorSearching {
+		m.showHelp = true
+		return m, nil
+	}
+	if m.phase == PhaseExploring {
+		return m.handleExploreKey(msg)
+	}
+	if m.errorSearching {
+		return m.handleErrorSearchKey(msg)
+	}
+	if m.inspecting {
+		return m.handleInspectKey(msg)
+	}
+	if m.viewingTimeline {
+		return m.handleTimelineKey(msg)
+	}
 
 	switch {
 	case key.Matches(msg, keys.Quit):
-		if m.phase == PhaseComplete || m.phase == PhaseExploring || msg.String() == "ctrl+c" {
+		if m.phase == PhaseComplete || m.phase == PhaseExploring {
 			m.quitting = true
 			return m, tea.Quit
 		}
 		return m, nil
 
+	case key.Matches(msg, keys.Stop):
+		if m.phase == PhaseRunning && !m.draining && m.onInterrupt != nil {
+			m.drain()
+		}
+		return m, nil
+
 	case key.Matches(msg, keys.Tab):
 		switch m.activePanel {
+		case PanelRunning:
+			m.activePanel = PanelWorkers
 		case PanelWorkers:
 			m.activePanel = PanelErrors
 		case PanelErrors:
-			m.activePanel = PanelWorkers
+			if m.phase == PhaseRunning {
+				m.activePanel = PanelRunning
+			} else {
+				m.activePanel = PanelWorkers
+			}
+		}
+		return m, nil
+
+	case key.Matches(msg, keys.Explore):
+		if m.phase == PhaseComplete {
+			m.enterExplore()
+		}
+		return m, nil
+
+	case key.Matches(msg, keys.Timeline):
+		if m.phase == PhaseComplete && len(m.timeline.Workers) > 0 {
+			m.viewingTimeline = true
 		}
 		return m, nil
 
@@ -121,21 +242,82 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.moveCursor(10)
 		return m, nil
 
+	case key.Matches(msg, keys.Home):
+		m.moveCursor(-m.cursorLimit())
+		return m, nil
+
+	case key.Matches(msg, keys.End):
+		m.moveCursor(m.cursorLimit())
+		return m, nil
+
 	case key.Matches(msg, keys.Copy):
 		return m.copyError()
 
+	case key.Matches(msg, keys.Open):
+		return m.openInEditor()
+
+	case key.Matches(msg, keys.Rerun):
+		if e := m.selectedError(); e != nil && m.activePanel == PanelErrors {
+			return m, m.rerun(e)
+		}
+		return m, nil
+
+	case key.Matches(msg, keys.RerunAll):
+		return m, m.rerunFailed()
+
+	case key.Matches(msg, keys.Search):
+		m.activePanel = PanelErrors
+		m.errorSearching = true
+		return m, nil
+
+	case key.Matches(msg, keys.Back):
+		if m.errorSearch != "" {
+			m.setErrorSearch("", m.errorRegex)
+			m.errorCursor = 0
+		}
+		return m, nil
+
+	case key.Matches(msg, keys.Group):
+		m.groupErrors = !m.groupErrors
+		m.errorCursor = 0
+		m.errorOffset = 0
+		return m, nil
+
+	case key.Matches(msg, keys.Next):
+		if m.activePanel == PanelErrors {
+			m.jumpToMatch(1)
+		}
+		return m, nil
+
+	case key.Matches(msg, keys.Previous):
+		if m.activePanel == PanelErrors {
+			m.jumpToMatch(-1)
+		}
+		return m, nil
+
 	}
 
 	return m, nil
 }
 
+// drain asks the runner to stop after the current tests. The callback runs on
+// its own goroutine as it reports back to the program through Send.
+func (m *Model) drain() {
+	m.draining = true
+	go m.onInterrupt()
+}
+
 func (m *Model) moveCursor(delta int) {
 	switch m.activePanel {
+	case PanelRunning:
+		running := len(m.getRunningTests())
+		m.runningCursor = max(min(m.runningCursor+delta, running-1), 0)
+
 	case PanelWorkers:
-		m.workersOffset += delta
+		m.moveWorkerCursor(delta)
 
 	case PanelErrors:
-		maxCursor := len(m.errors) - 1
+		maxCursor := len(m.errorRows()) - 1
 		m.errorCursor += delta
 		if m.errorCursor < 0 {
 			m.errorCursor = 0
@@ -146,42 +328,68 @@ func (m *Model) moveCursor(delta int) {
 	}
 }
 
+// cursorLimit returns a move large enough to reach either end of any panel.
+func (m *Model) cursorLimit() int {
+	return len(m.errorRows()) + len(m.workerOrder) + len(m.getRunningTests())
+}
+
 func (m *Model) copyError() (tea.Model, tea.Cmd) {
-	if m.activePanel != PanelErrors || len(m.errors) == 0 {
+	if m.activePanel != PanelErrors {
 		return m, nil
 	}
 
-	if m.errorCursor < 0 || m.errorCursor >= len(m.errors) {
+	e := m.selectedError()
+	if e == nil {
 		return m, nil
 	}
-
-	e := m.errors[m.errorCursor]
 	var parts []string
 	parts = append(parts, e.TestName)
 	if e.Message != "" {
 		parts = append(parts, e.Message)
 	}
+	if e.Comparison {
+		diff := []string{"--- Expected", "+++ Actual"}
+		for _, line := range e.Diff {
+			diff = append(diff, line.Prefix()+line.Text())
+		}
+		parts = append(parts, strings.Join(diff, "\n"))
+	}
 	if e.Details != "" {
 		parts = append(parts, e.Details)
 	}
 	text := strings.Join(parts, "\n\n")
 
 	if err := clipboard.WriteAll(text); err != nil {
-		m.copyNotice = fmt.Sprintf("Copy failed: %s", err)
-	} else {
-		m.copyNotice = "Copied to clipboard!"
+		return m, m.notify(fmt.Sprintf("Copy failed: %s", err))
 	}
+	return m, m.notify("Copied to clipboard!")
+}
 
-	return m, tea.Tick(2*time.Second, func(_ time.Time) tea.Msg {
+// notify shows a message in place of the help bar for a couple of seconds.
+func (m *Model) notify(notice string) tea.Cmd {
+	m.copyNotice = notice
+	return tea.Tick(2*time.Second, func(_ time.Time) tea.Msg {
 		return CopyNoticeExpiredMsg{}
 	})
 }
 
 func (m *Model) toggle() {
+	if m.activePanel == PanelWorkers {
+		m.inspecting = true
+		m.inspectOffset = 0
+		return
+	}
 	if m.activePanel == PanelErrors {
-		if m.errorCursor >= 0 && m.errorCursor < len(m.errors) {
-			m.errors[m.errorCursor].Expanded = !m.errors[m.errorCursor].Expanded
+		rows := m.errorRows()
+		if m.errorCursor < 0 || m.errorCursor >= len(rows) {
+			return
+		}
+		if g := rows[m.errorCursor].group; g != nil {
+			m.expandedGroups[g.message] = !m.expandedGroups[g.message]
+			return
 		}
+		e := &m.errors[rows[m.errorCursor].index]
+		e.Expanded = !e.Expanded
 	}
 }
 
@@ -192,17 +400,49 @@ func (m *Model) handleWorkerStart(msg WorkerStartMsg) {
 	}
 }
 
-func (m *Model) handleTestStart(msg TestStartMsg) {
-	w := m.workers[msg.WorkerID]
-	if w == nil {
+func (m *Model) handleWorkerHook(msg WorkerHookMsg) {
+	if w, ok := m.workers[msg.WorkerID]; ok {
+		w.HookTime += msg.Result.Duration
+		w.Hooks = append(w.Hooks, msg.Result)
+	}
+	m.hookTime += msg.Result.Duration
+
+	if msg.Result.Err == nil {
 		return
 	}
 
-	for _, t := range w.Tests {
-		if t.Key == msg.TestKey {
-			t.Status = StatusRunning
-			return
+	details := msg.Result.Output
+	if msg.Result.LogPath != "" {
+		details += "\n\nFull log: " + msg.Result.LogPath
+	}
+	m.hookErrors++
+	m.errors = append(m.errors, ErrorEntry{
+		TestName: fmt.Sprintf("Worker %d: %s", msg.WorkerID+1, msg.Result.Hook),
+		Message:  msg.Result.Err.Error(),
+		Details:  details,
+		WorkerID: msg.WorkerID,
+	})
+}
+
+func (m *Model) handleWorkerUsage(msg WorkerUsageMsg) {
+	if w, ok := m.workers[msg.WorkerID]; ok {
+		w.Memory = msg.Usage.RSS
+		w.PeakMemory = max(w.PeakMemory, msg.Usage.PeakRSS)
+		w.CPUTime = msg.Usage.CPUTime
+		if msg.Usage.CurrentFile != w.CurrentFile {
+			w.FileStarted = time.Now()
 		}
+		w.CurrentFile = msg.Usage.CurrentFile
+	}
+	if file := msg.Usage.CurrentFile; file != "" {
+		m.filePeaks[file] = max(m.filePeaks[file], msg.Usage.FilePeak)
+	}
+}
+
+func (m *Model) handleTestStart(msg TestStartMsg) {
+	w := m.workers[msg.WorkerID]
+	if w == nil {
+		return
 	}
 
 	w.Tests = append(w.Tests, &TestNode{
@@ -210,6 +450,8 @@ func (m *Model) handleTestStart(msg TestStartMsg) {
 		Name:     msg.DisplayName,
 		Status:   StatusRunning,
 		WorkerID: msg.WorkerID,
+		File:     msg.File,
+		Suite:    msg.Suite,
 	})
 }
 
@@ -219,15 +461,14 @@ func (m *Model) handleTestPass(msg TestPassMsg) {
 		return
 	}
 
-	for _, t := range w.Tests {
-		if t.Key == msg.TestName {
-			if t.Status != StatusFailed {
-				t.Status = StatusPassed
-				w.Completed++
-				m.totalComplete++
-			}
-			return
+	if t := w.current(msg.TestName); t != nil {
+		t.Duration = msg.Duration
+		if t.Status != StatusFailed && t.Status != StatusSkipped {
+			t.Status = StatusPassed
+			w.Completed++
+			m.totalComplete++
 		}
+		return
 	}
 
 	w.Tests = append(w.Tests, &TestNode{
@@ -246,62 +487,76 @@ func (m *Model) handleTestFail(msg TestFailMsg) {
 		return
 	}
 
-	for _, t := range w.Tests {
-		if t.Key == msg.TestName {
-			t.Status = StatusFailed
-			t.ErrorMessage = msg.Message
-			t.ErrorDetails = msg.Details
-			w.Completed++
-			w.Failed++
-			m.totalComplete++
-			m.totalFailed++
-			m.errors = append(m.errors, ErrorEntry{
-				TestName: t.Name,
-				Message:  msg.Message,
-				Details:  msg.Details,
-				WorkerID: msg.WorkerID,
-				Expanded: false,
-			})
-			return
-		}
+	if t := w.current(msg.TestName); t != nil {
+		t.Status = StatusFailed
+		t.ErrorMessage = msg.Message
+		t.ErrorDetails = msg.Details
+		w.Completed++
+		w.Failed++
+		m.totalComplete++
+		m.totalFailed++
+		m.addError(t, msg)
+		return
 	}
 
-	w.Tests = append(w.Tests, &TestNode{
+	t := &TestNode{
 		Key:          msg.TestName,
 		Name:         msg.TestName,
 		Status:       StatusFailed,
 		ErrorMessage: msg.Message,
 		ErrorDetails: msg.Details,
 		WorkerID:     msg.WorkerID,
-	})
+	}
+	w.Tests = append(w.Tests, t)
 	w.Completed++
 	w.Failed++
 	m.totalComplete++
 	m.totalFailed++
+	m.addError(t, msg)
+}
+
+func (m *Model) addError(t *TestNode, msg TestFailMsg) {
+	testName := t.Name
+	quarantined := m.quarantine.Has(testName)
+	if quarantined {
+		m.totalQuarantined++
+	}
 	m.errors = append(m.errors, ErrorEntry{
-		TestName: msg.TestName,
-		Message:  msg.Message,
-		Details:  msg.Details,
-		WorkerID: msg.WorkerID,
-		Expanded: false,
+		test:        t,
+		TestName:    testName,
+		Message:     msg.Message,
+		Details:     msg.Details,
+		WorkerID:    msg.WorkerID,
+		Expanded:    false,
+		Flaky:       m.knownFlaky.Has(testName),
+		Quarantined: quarantined,
+		Comparison:  msg.Comparison,
+		Diff:        comparisonDiff(msg.Expected, msg.Actual, msg.Comparison),
 	})
 }
 
+// comparisonDiff returns the diff shown for a failed comparison, or nil when
+// the failure wasn't a comparison.
+func comparisonDiff(expected, actual string, comparison bool) []output.DiffLine {
+	if !comparison {
+		return nil
+	}
+	return output.Diff(expected, actual, 3)
+}
+
 func (m *Model) handleTestSkip(msg TestSkipMsg) {
 	w := m.workers[msg.WorkerID]
 	if w == nil {
 		return
 	}
 
-	for _, t := range w.Tests {
-		if t.Key == msg.TestName {
-			t.Status = StatusSkipped
-			t.ErrorMessage = msg.Message
-			w.Completed++
-			m.totalComplete++
-			m.totalSkipped++
-			return
-		}
+	if t := w.current(msg.TestName); t != nil {
+		t.Status = StatusSkipped
+		t.ErrorMessage = msg.Message
+		w.Completed++
+		m.totalComplete++
+		m.totalSkipped++
+		return
 	}
 
 	w.Tests = append(w.Tests, &TestNode{
@@ -322,12 +577,16 @@ func (m *Model) handleTestCount(msg TestCountMsg) {
 		return
 	}
 
+	w.CountedTests += msg.Count
+	w.CountedFiles += w.BatchFiles
+	total := output.EstimateTestCount(w.CountedTests, w.CountedFiles, w.TestFiles)
+
 	if !w.HasTestCount {
-		m.testCount = m.testCount - w.TestFiles + msg.Count
+		m.testCount = m.testCount - w.TestFiles + total
 	} else {
-		m.testCount = m.testCount - w.Total + msg.Count
+		m.testCount = m.testCount - w.Total + total
 	}
-	w.Total = msg.Count
+	w.Total = total
 	w.HasTestCount = true
 	m.hasTestCount = true
 }
@@ -2,13 +2,14 @@ package tui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
-)
 
-var styles = DefaultStyles()
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
 
 func (m *Model) View() string {
 	if m.quitting {
@@ -24,6 +25,32 @@ func (m *Model) View() string {
 	b.WriteString("\n\n")
 
 	contentHeight := max(m.height-8, 8)
+	m.panelAreas = make(map[Panel]area)
+
+	if m.showHelp {
+		b.WriteString(m.renderHelp(contentHeight + 1))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelpBar())
+		return b.String()
+	}
+	if m.phase == PhaseExploring {
+		b.WriteString(m.renderExplore(contentHeight + 1))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelpBar())
+		return b.String()
+	}
+	if m.viewingTimeline {
+		b.WriteString(m.styles.ActivePanel.Width(m.width - 2).Height(contentHeight + 1).Render(m.renderTimeline(contentHeight + 1)))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelpBar())
+		return b.String()
+	}
+	if m.inspecting {
+		b.WriteString(m.renderWorkerDetail(contentHeight + 1))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelpBar())
+		return b.String()
+	}
 
 	leftWidth := max((m.width-5)/2, 20)
 	rightWidth := max(m.width-leftWidth-5, 20)
@@ -48,28 +75,34 @@ func (m *Model) View() string {
 	}
 	bottomLeftPanel := m.renderWorkersPanel(leftInnerWidth, workersHeight-2)
 
-	topLeftStyle := styles.Panel.Width(leftWidth).Height(runningHeight)
-	bottomLeftStyle := styles.Panel.Width(leftWidth).Height(workersHeight)
+	topLeftStyle := m.styles.Panel.Width(leftWidth).Height(runningHeight)
+	bottomLeftStyle := m.styles.Panel.Width(leftWidth).Height(workersHeight)
 
 	if m.activePanel == PanelRunning && m.phase == PhaseRunning {
-		topLeftStyle = styles.ActivePanel.Width(leftWidth).Height(runningHeight)
+		topLeftStyle = m.styles.ActivePanel.Width(leftWidth).Height(runningHeight)
 	}
 	if m.activePanel == PanelWorkers {
-		bottomLeftStyle = styles.ActivePanel.Width(leftWidth).Height(workersHeight)
+		bottomLeftStyle = m.styles.ActivePanel.Width(leftWidth).Height(workersHeight)
 	}
 
 	topLeft := topLeftStyle.Render(topLeftPanel)
 	bottomLeft := bottomLeftStyle.Render(bottomLeftPanel)
+	top := strings.Count(b.String(), "\n")
+	if m.phase == PhaseRunning {
+		m.panelAreas[PanelRunning] = area{0, top, lipgloss.Width(topLeft), lipgloss.Height(topLeft)}
+	}
+	m.panelAreas[PanelWorkers] = area{0, top + lipgloss.Height(topLeft), lipgloss.Width(bottomLeft), lipgloss.Height(bottomLeft)}
 	leftColumn := lipgloss.JoinVertical(lipgloss.Left, topLeft, bottomLeft)
 
 	rightInnerWidth := rightWidth - 4
 	errorsPanelHeight := contentHeight + 1
 	errorsPanel := m.renderErrorsPanel(errorsPanelHeight, rightInnerWidth)
-	errorsStyle := styles.Panel.Width(rightWidth).Height(errorsPanelHeight)
+	errorsStyle := m.styles.Panel.Width(rightWidth).Height(errorsPanelHeight)
 	if m.activePanel == PanelErrors {
-		errorsStyle = styles.ActivePanel.Width(rightWidth).Height(errorsPanelHeight)
+		errorsStyle = m.styles.ActivePanel.Width(rightWidth).Height(errorsPanelHeight)
 	}
 	rightColumn := errorsStyle.Render(errorsPanel)
+	m.panelAreas[PanelErrors] = area{lipgloss.Width(leftColumn) + 1, top, lipgloss.Width(rightColumn), lipgloss.Height(rightColumn)}
 
 	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, leftColumn, " ", rightColumn))
 	b.WriteString("\n")
@@ -86,45 +119,75 @@ func (m *Model) getElapsed() time.Duration {
 	return time.Since(m.startTime)
 }
 
+// stoppedEarly reports whether the run was stopped before every worker had
+// finished all of its files.
+func (m *Model) stoppedEarly() bool {
+	if !m.draining {
+		return false
+	}
+	for _, w := range m.workers {
+		if len(w.DoneFiles) < w.TestFiles {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Model) renderHeader() string {
 	elapsed := m.getElapsed().Round(time.Second)
 
 	var status string
 	switch m.phase {
 	case PhaseRunning:
-		status = styles.TestRunning.Render("Running")
+		if m.draining {
+			status = m.styles.TestSkipped.Render(m.styles.Icons.Text("Stopping after current tests…"))
+		} else {
+			status = m.styles.TestRunning.Render("Running")
+		}
 	case PhaseCleanup:
-		status = styles.TestRunning.Render(fmt.Sprintf("Cleaning up workers... %d/%d", m.cleanupCompleted, m.cleanupTotal))
+		status = m.styles.TestRunning.Render(fmt.Sprintf("Cleaning up workers... %d/%d", m.cleanupCompleted, m.cleanupTotal))
 	case PhaseComplete, PhaseExploring:
-		if m.totalFailed > 0 {
-			status = styles.TestFailed.Render("Complete - FAILED")
+		label := "Complete"
+		if m.stoppedEarly() {
+			label = "Stopped"
+		}
+		if m.failed() {
+			status = m.styles.TestFailed.Render(label + " - FAILED")
 		} else {
-			status = styles.TestPassed.Render("Complete - PASSED")
+			status = m.styles.TestPassed.Render(label + " - PASSED")
 		}
 	}
 
-	title := styles.Title.Render("PHPUnit Parallel")
+	title := m.styles.Title.Render("PHPUnit Parallel")
 	header := fmt.Sprintf("%s - %s (%s elapsed)", title, status, elapsed)
 
 	if args := m.renderArgs(); args != "" {
-		header += "  " + styles.Dim.Render(args)
+		header += "  " + m.styles.Dim.Render(args)
 	}
 
 	return header
 }
 
+// renderArgs renders the run's options as they would be typed in a shell.
 func (m *Model) renderArgs() string {
-	var parts []string
+	var args []string
 	if m.filter != "" {
-		parts = append(parts, "--filter "+m.filter)
+		args = append(args, "--filter", m.filter)
 	}
 	if m.group != "" {
-		parts = append(parts, "--group "+m.group)
+		args = append(args, "--group", m.group)
 	}
 	if m.excludeGroup != "" {
-		parts = append(parts, "--exclude-group "+m.excludeGroup)
+		args = append(args, "--exclude-group", m.excludeGroup)
+	}
+	if m.seed != 0 {
+		args = append(args, "--seed", strconv.FormatInt(m.seed, 10))
+	}
+	if len(m.phpunitArgs) > 0 {
+		args = append(args, "--")
+		args = append(args, m.phpunitArgs...)
 	}
-	return strings.Join(parts, " ")
+	return output.ShellJoin(args)
 }
 
 func (m *Model) renderOverallProgress() string {
@@ -133,39 +196,53 @@ func (m *Model) renderOverallProgress() string {
 	failed := m.totalFailed
 	elapsed := m.getElapsed()
 
+	// With history, progress is weighted by each file's recorded time
+	estimating := m.phase == PhaseRunning && m.estimating()
+	fraction, remaining := m.estimate()
+
 	var statsLine string
-	if m.hasTestCount {
+	switch {
+	case estimating && m.hasTestCount:
+		statsLine = fmt.Sprintf("Overall: %d/%d (%d%%)", completed, total, int(fraction*100))
+	case estimating:
+		statsLine = fmt.Sprintf("Overall: %d test files (%d%%)", m.testCount, int(fraction*100))
+	case m.hasTestCount:
 		percent := 0
 		if total > 0 {
 			percent = (completed * 100) / total
 		}
 		statsLine = fmt.Sprintf("Overall: %d/%d (%d%%)", completed, total, percent)
-	} else {
+	default:
 		statsLine = fmt.Sprintf("Overall: %d test files", m.testCount)
 	}
 
 	if failed > 0 {
-		statsLine += styles.TestFailed.Render(fmt.Sprintf(" %d failed", failed))
+		statsLine += m.styles.TestFailed.Render(fmt.Sprintf(" %d failed", failed))
 	}
 
 	var etaLine string
-	if m.phase == PhaseRunning && m.hasTestCount && completed > 0 && total > 0 {
+	if estimating {
+		etaLine = m.styles.Dim.Render(fmt.Sprintf("  ETA: %s remaining (est. %s total)", formatDuration(remaining), formatDuration(elapsed+remaining)))
+	} else if m.phase == PhaseRunning && m.hasTestCount && completed > 0 && total > 0 {
 		estimatedTotal := time.Duration(float64(elapsed) * float64(total) / float64(completed))
 		remaining := max(estimatedTotal-elapsed, 0)
-		etaLine = styles.Dim.Render(fmt.Sprintf("  ETA: %s remaining (est. %s total)", formatDuration(remaining), formatDuration(estimatedTotal)))
+		etaLine = m.styles.Dim.Render(fmt.Sprintf("  ETA: %s remaining (est. %s total)", formatDuration(remaining), formatDuration(estimatedTotal)))
 	} else if m.phase != PhaseRunning {
-		etaLine = styles.Dim.Render(fmt.Sprintf("  Completed in %s", formatDuration(elapsed)))
+		etaLine = m.styles.Dim.Render(fmt.Sprintf("  Completed in %s", formatDuration(elapsed)))
 	}
 
 	barWidth := max(m.width-2, 20)
 	bar := m.buildProgressBar(completed, failed, total, barWidth, false)
+	if estimating {
+		bar = m.weightedProgressBar(fraction, completed, failed, barWidth, false)
+	}
 
 	return statsLine + etaLine + "\n" + bar
 }
 
 func (m *Model) buildProgressBar(completed, failed, total, width int, dimmed bool) string {
 	if total == 0 {
-		return styles.Dim.Render("[" + strings.Repeat("░", width) + "]")
+		return m.styles.Dim.Render("[" + strings.Repeat(m.styles.Icons.BarEmpty, width) + "]")
 	}
 
 	filledWidth := (completed * width) / total
@@ -184,65 +261,82 @@ func (m *Model) buildProgressBar(completed, failed, total, width int, dimmed boo
 	remaining := width - filledWidth
 
 	if dimmed {
-		return styles.Dim.Render("["+strings.Repeat("█", passedWidth)) +
-			styles.TestFailed.Render(strings.Repeat("█", failedWidth)) +
-			styles.Dim.Render(strings.Repeat("░", remaining)+"]")
+		return m.styles.Dim.Render("["+strings.Repeat(m.styles.Icons.BarFull, passedWidth)) +
+			m.styles.TestFailed.Render(strings.Repeat(m.styles.Icons.BarFull, failedWidth)) +
+			m.styles.Dim.Render(strings.Repeat(m.styles.Icons.BarEmpty, remaining)+"]")
 	}
 
-	return styles.Dim.Render("[") +
-		styles.TestPassed.Render(strings.Repeat("█", passedWidth)) +
-		styles.TestFailed.Render(strings.Repeat("█", failedWidth)) +
-		styles.Dim.Render(strings.Repeat("░", remaining)+"]")
+	return m.styles.Dim.Render("[") +
+		m.styles.TestPassed.Render(strings.Repeat(m.styles.Icons.BarFull, passedWidth)) +
+		m.styles.TestFailed.Render(strings.Repeat(m.styles.Icons.BarFull, failedWidth)) +
+		m.styles.Dim.Render(strings.Repeat(m.styles.Icons.BarEmpty, remaining)+"]")
 }
 
 func (m *Model) renderWorkersPanel(panelWidth int, panelHeight int) string {
 	var lines []string
-	lines = append(lines, styles.Bold.Render("Workers"))
+	lines = append(lines, m.styles.Bold.Render("Workers"))
 	lines = append(lines, "")
 
 	barWidth := max(panelWidth-2, 10)
 
-	sortedWorkers := make([]int, 0, len(m.workerOrder))
-	var finishedWorkers []int
-	for _, id := range m.workerOrder {
-		w := m.workers[id]
-		if w.HasTestCount && w.Completed >= w.Total {
-			finishedWorkers = append(finishedWorkers, id)
-		} else {
-			sortedWorkers = append(sortedWorkers, id)
-		}
-	}
-	sortedWorkers = append(sortedWorkers, finishedWorkers...)
+	sortedWorkers := m.sortedWorkers()
+	cursorIndex := 0
 
 	var workerLines []string
-	for _, id := range sortedWorkers {
+	for i, id := range sortedWorkers {
 		w := m.workers[id]
 		isComplete := w.HasTestCount && w.Completed >= w.Total
 
+		estimating := m.phase == PhaseRunning && m.estimating() && len(w.Files) > 0 && !isComplete
+		var fraction float64
+		if estimating {
+			done, total := m.workerWork(w)
+			fraction = float64(done) / float64(max(total, 1))
+		}
+
 		var statsLine string
 		if w.HasTestCount {
 			percent := 0
-			if w.Total > 0 {
+			if estimating {
+				percent = int(fraction * 100)
+			} else if w.Total > 0 {
 				percent = (w.Completed * 100) / w.Total
 			}
 			baseLine := fmt.Sprintf("Worker %d: %d/%d (%d%%)", id+1, w.Completed, w.Total, percent)
 			if isComplete {
-				baseLine = styles.Dim.Render(baseLine)
+				baseLine = m.styles.Dim.Render(baseLine)
 			}
 			statsLine = baseLine
 			if w.Failed > 0 {
-				statsLine += styles.TestFailed.Render(fmt.Sprintf(" %d failed", w.Failed))
+				statsLine += m.styles.TestFailed.Render(fmt.Sprintf(" %d failed", w.Failed))
+			}
+			if w.Memory > 0 && !isComplete {
+				statsLine += m.styles.Dim.Render(" " + output.FormatBytes(w.Memory))
+			}
+			if estimating {
+				statsLine += m.styles.Dim.Render(" ETA " + formatDuration(m.workerETA(w)))
 			}
 		} else {
 			statsLine = fmt.Sprintf("Worker %d: %d files", id+1, w.TestFiles)
 			if isComplete {
-				statsLine = styles.Dim.Render(statsLine)
+				statsLine = m.styles.Dim.Render(statsLine)
+			}
+			if estimating {
+				statsLine += m.styles.Dim.Render(" ETA " + formatDuration(m.workerETA(w)))
+			}
+		}
+		if id == m.workerCursor {
+			cursorIndex = i
+			if m.activePanel == PanelWorkers {
+				statsLine = m.styles.Cursor.Render(statsLine)
 			}
 		}
 		workerLines = append(workerLines, statsLine)
 
 		var workerBar string
-		if w.HasTestCount {
+		if estimating {
+			workerBar = m.weightedProgressBar(fraction, w.Completed, w.Failed, barWidth, isComplete)
+		} else if w.HasTestCount {
 			workerBar = m.buildProgressBar(w.Completed, w.Failed, w.Total, barWidth, isComplete)
 		} else {
 			workerBar = m.buildProgressBar(0, 0, 0, barWidth, isComplete)
@@ -263,8 +357,8 @@ func (m *Model) renderWorkersPanel(panelWidth int, panelHeight int) string {
 		totalPages = (totalWorkers + workersPerPage - 1) / workersPerPage
 	}
 
-	m.workersOffset = max(m.workersOffset, 0)
-	m.workersOffset = min(m.workersOffset, totalPages-1)
+	// Show the page holding the selected worker
+	m.workersOffset = min(cursorIndex/workersPerPage, totalPages-1)
 	currentPage := m.workersOffset
 
 	startWorker := currentPage * workersPerPage
@@ -275,7 +369,7 @@ func (m *Model) renderWorkersPanel(panelWidth int, panelHeight int) string {
 	lines = append(lines, workerLines[startLine:endLine]...)
 
 	if needsPagination {
-		pageInfo := styles.Dim.Render(fmt.Sprintf("Page %d/%d (↑↓)", currentPage+1, totalPages))
+		pageInfo := m.styles.Dim.Render(m.styles.Icons.Text(fmt.Sprintf("Page %d/%d (↑↓)", currentPage+1, totalPages)))
 		lines = append(lines, pageInfo)
 	}
 
@@ -295,10 +389,10 @@ func (m *Model) renderSummaryPanel(panelWidth int, _ int) string {
 	cumulativeTime := elapsed * time.Duration(m.workerCount)
 
 	var resultText string
-	if m.totalFailed > 0 {
-		resultText = styles.TestFailed.Render("  FAILED  ")
+	if m.failed() {
+		resultText = m.styles.TestFailed.Render("  FAILED  ")
 	} else {
-		resultText = styles.TestPassed.Render("  PASSED  ")
+		resultText = m.styles.TestPassed.Render("  PASSED  ")
 	}
 	resultPadding := max((panelWidth-visibleLength(resultText))/2, 0)
 
@@ -319,25 +413,60 @@ func (m *Model) renderSummaryPanel(panelWidth int, _ int) string {
 	lines = append(lines, formatRow("Duration:", formatDuration(elapsed)))
 	lines = append(lines, formatRow("Cumulative:", formatDuration(cumulativeTime)))
 	lines = append(lines, formatRow("Rate:", fmt.Sprintf("%.1f tests/sec", testsPerSec)))
+	if m.hookTime > 0 {
+		hookPercent := 0
+		if cumulativeTime > 0 {
+			hookPercent = int(m.hookTime * 100 / cumulativeTime)
+		}
+		lines = append(lines, formatRow("Hooks:", fmt.Sprintf("%s (%d%%)", formatDuration(m.hookTime), hookPercent)))
+	}
 	lines = append(lines, "")
 
 	passed := m.totalComplete - m.totalFailed - m.totalSkipped
 	lines = append(lines, formatRow("Total:", fmt.Sprintf("%d tests", m.totalComplete)))
-	lines = append(lines, formatRow("Passed:", fmt.Sprintf("%d", passed), styles.TestPassed))
+	lines = append(lines, formatRow("Passed:", fmt.Sprintf("%d", passed), m.styles.TestPassed))
 
 	if m.totalFailed > 0 {
-		lines = append(lines, formatRow("Failed:", fmt.Sprintf("%d", m.totalFailed), styles.TestFailed))
+		lines = append(lines, formatRow("Failed:", fmt.Sprintf("%d", m.totalFailed), m.styles.TestFailed))
+	}
+	if m.totalQuarantined > 0 {
+		lines = append(lines, formatRow("Quarantined:", fmt.Sprintf("%d", m.totalQuarantined), m.styles.TestSkipped))
 	}
 	if m.totalSkipped > 0 {
-		lines = append(lines, formatRow("Skipped:", fmt.Sprintf("%d", m.totalSkipped), styles.TestSkipped))
+		lines = append(lines, formatRow("Skipped:", fmt.Sprintf("%d", m.totalSkipped), m.styles.TestSkipped))
+	}
+	if m.hookErrors > 0 {
+		lines = append(lines, formatRow("Hook errors:", fmt.Sprintf("%d", m.hookErrors), m.styles.TestFailed))
 	}
 
 	lines = append(lines, "")
-	lines = append(lines, formatRow("Workers:", fmt.Sprintf("%d", m.workerCount), styles.Dim))
+	lines = append(lines, formatRow("Workers:", fmt.Sprintf("%d", m.workerCount), m.styles.Dim))
+
+	if file, peak := m.peakMemoryFile(); peak > 0 {
+		lines = append(lines, formatRow("Peak memory:", output.FormatBytes(peak), m.styles.Dim))
+		lines = append(lines, m.styles.Dim.Render(truncateName(file, panelWidth)))
+	}
 
 	return strings.Join(lines, "\n")
 }
 
+func (m *Model) peakMemoryFile() (string, uint64) {
+	var file string
+	var peak uint64
+	for f, p := range m.filePeaks {
+		if p > peak || (p == peak && f < file) {
+			file, peak = f, p
+		}
+	}
+	return file, peak
+}
+
+// failed reports whether the run failed. Failures of quarantined tests are
+// shown but do not fail the run.
+func (m *Model) failed() bool {
+	return m.totalFailed > m.totalQuarantined || m.hookErrors > 0
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Second {
 		return fmt.Sprintf("%dms", d.Milliseconds())
@@ -355,14 +484,14 @@ func (m *Model) renderRunningPanel(height int, panelWidth int) string {
 
 	runningTests := m.getRunningTests()
 	title := fmt.Sprintf("Running (%d)", len(runningTests))
-	lines = append(lines, styles.Bold.Render(title))
+	lines = append(lines, m.styles.Bold.Render(title))
 	lines = append(lines, "")
 
 	if len(runningTests) == 0 {
 		if m.phase == PhaseRunning {
-			lines = append(lines, styles.Dim.Render("Waiting..."))
+			lines = append(lines, m.styles.Dim.Render("Waiting..."))
 		} else {
-			lines = append(lines, styles.Dim.Render("Complete"))
+			lines = append(lines, m.styles.Dim.Render("Complete"))
 		}
 		return strings.Join(lines, "\n")
 	}
@@ -370,11 +499,11 @@ func (m *Model) renderRunningPanel(height int, panelWidth int) string {
 	maxNameLen := max(panelWidth-4, 10)
 
 	for i, t := range runningTests {
-		icon := styles.IconRunning
-		line := fmt.Sprintf("%s %s", styles.TestRunning.Render(icon), truncateName(t.Name, maxNameLen))
+		icon := m.styles.Icons.Running
+		line := fmt.Sprintf("%s %s", m.styles.TestRunning.Render(icon), truncateName(t.Name, maxNameLen))
 
 		if m.activePanel == PanelRunning && i == m.runningCursor {
-			line = styles.Cursor.Render(line)
+			line = m.styles.Cursor.Render(line)
 		}
 		lines = append(lines, line)
 	}
@@ -403,12 +532,43 @@ func (m *Model) renderRunningPanel(height int, panelWidth int) string {
 
 func (m *Model) renderErrorsPanel(height int, panelWidth int) string {
 	var lines []string
+	m.errorLines = nil
+	rows := m.errorRows()
 	title := fmt.Sprintf("Errors (%d)", len(m.errors))
-	lines = append(lines, styles.Bold.Render(title))
+	if m.errorSearch != "" || m.errorSearching {
+		matching := 0
+		for _, r := range rows {
+			if r.group != nil {
+				matching += len(r.group.indexes)
+			} else if r.depth == 0 {
+				matching++
+			}
+		}
+		title = fmt.Sprintf("Errors (%d/%d)", matching, len(m.errors))
+	}
+	title = m.styles.Bold.Render(title)
+	if m.errorSearch != "" || m.errorSearching {
+		query := "/" + m.errorSearch
+		if m.errorSearching {
+			query += m.styles.Icons.Caret
+		}
+		title += " " + query
+		if m.errorRegex {
+			title += m.styles.Dim.Render(" [regex]")
+		}
+		if m.invalidErrorSearch() {
+			title += m.styles.TestFailed.Render(" (invalid)")
+		}
+	}
+	lines = append(lines, title)
 	lines = append(lines, "")
 
 	if len(m.errors) == 0 {
-		lines = append(lines, styles.Dim.Render("No errors"))
+		lines = append(lines, m.styles.Dim.Render("No errors"))
+		return strings.Join(lines, "\n")
+	}
+	if len(rows) == 0 {
+		lines = append(lines, m.styles.Dim.Render("No matching errors"))
 		return strings.Join(lines, "\n")
 	}
 
@@ -416,35 +576,97 @@ func (m *Model) renderErrorsPanel(height int, panelWidth int) string {
 	cursorStart := 0
 	cursorEnd := 0
 
-	for i, e := range m.errors {
-		expandIcon := styles.IconCollaps
-		if e.Expanded {
-			expandIcon = styles.IconExpand
+	// Track the row each line belongs to so that clicks can be mapped back
+	lineRows := []errorLine{{row: -1}, {row: -1}}
+	markRow := func(row int) {
+		head := true
+		for len(lineRows) < len(lines) {
+			lineRows = append(lineRows, errorLine{row: row, head: head})
+			head = false
 		}
+	}
 
+	for i, row := range rows {
+		markRow(i - 1)
 		if i == m.errorCursor {
 			cursorStart = len(lines) - 2
 		}
 
-		line := fmt.Sprintf("%s %s", expandIcon, styles.TestFailed.Render(truncateName(e.TestName, maxNameLen)))
+		if g := row.group; g != nil {
+			expandIcon := m.styles.Icons.Collapse
+			if m.expandedGroups[g.message] {
+				expandIcon = m.styles.Icons.Expand
+			}
+			count := m.styles.Icons.Text(fmt.Sprintf(" ×%d", len(g.indexes)))
+			message, _, _ := strings.Cut(g.message, "\n")
+			if message == "" {
+				message = "(no message)"
+			}
+			line := fmt.Sprintf("%s %s%s", expandIcon, m.styles.ErrorMsg.Render(truncateName(message, max(maxNameLen-len(count), 10))), m.styles.Bold.Render(count))
+			if m.activePanel == PanelErrors && i == m.errorCursor {
+				line = m.styles.Cursor.Render(line)
+			}
+			lines = append(lines, line)
+			if i == m.errorCursor {
+				cursorEnd = len(lines) - 2
+			}
+			continue
+		}
+
+		e := m.errors[row.index]
+		indent := strings.Repeat("  ", row.depth)
+		expandIcon := m.styles.Icons.Collapse
+		if e.Expanded {
+			expandIcon = m.styles.Icons.Expand
+		}
+
+		badge := ""
+		if e.Quarantined {
+			badge = " [quarantined]"
+		} else if e.Flaky {
+			badge = " [known flaky]"
+		}
+		switch e.Rerun {
+		case StatusRunning:
+			badge += " [re-running]"
+		case StatusPassed:
+			badge += " [passed on re-run]"
+		case StatusSkipped:
+			badge += " [skipped on re-run]"
+		case StatusFailed:
+			badge += " [failed on re-run]"
+		}
+		nameStyle := m.styles.TestFailed
+		if e.Quarantined || e.Rerun == StatusSkipped {
+			nameStyle = m.styles.TestSkipped
+		} else if e.Rerun == StatusPassed {
+			nameStyle = m.styles.TestPassed
+		}
+		nameLen := max(maxNameLen-len(badge)-len(indent), 10)
+		line := fmt.Sprintf("%s%s %s%s", indent, expandIcon, nameStyle.Render(truncateName(e.TestName, nameLen)), m.styles.Badge.Render(badge))
 		if m.activePanel == PanelErrors && i == m.errorCursor {
-			line = styles.Cursor.Render(line)
+			line = m.styles.Cursor.Render(line)
 		}
 		lines = append(lines, line)
 
 		if e.Expanded {
-			detailWidth := max(panelWidth-4, 10)
+			detailWidth := max(panelWidth-4-len(indent), 10)
 			if e.Message != "" {
 				msgLines := wrapText(e.Message, detailWidth)
 				for _, ml := range msgLines {
-					lines = append(lines, "  "+styles.ErrorMsg.Render(ml))
+					lines = append(lines, indent+"  "+m.styles.ErrorMsg.Render(ml))
+				}
+			}
+			if e.Comparison {
+				for _, dl := range m.renderDiff(e.Diff, detailWidth) {
+					lines = append(lines, indent+dl)
 				}
 			}
 			if e.Details != "" {
 				detailLines := strings.Split(e.Details, "\n")
 				for _, d := range detailLines {
 					if d != "" {
-						lines = append(lines, "  "+styles.ErrorDetail.Render(truncateName(d, detailWidth)))
+						lines = append(lines, indent+"  "+m.hyperlink(d, m.styles.ErrorDetail.Render(truncateName(d, detailWidth))))
 					}
 				}
 			}
@@ -455,6 +677,8 @@ func (m *Model) renderErrorsPanel(height int, panelWidth int) string {
 		}
 	}
 
+	markRow(len(rows) - 1)
+
 	visibleLines := max(height-2, 1)
 	if len(lines) > visibleLines+2 {
 		start := m.errorOffset
@@ -478,23 +702,124 @@ func (m *Model) renderErrorsPanel(height int, panelWidth int) string {
 		}
 		m.errorOffset = start
 		lines = append(headerLines, contentLines[start:start+min(visibleLines, len(contentLines))]...)
+		lineRows = append(lineRows[:2:2], lineRows[2+start:2+start+min(visibleLines, len(contentLines))]...)
 	}
+	m.errorLines = lineRows
 
 	return strings.Join(lines, "\n")
 }
 
+// renderDiff renders a unified diff of a failed comparison, highlighting the
+// changed words within replaced lines.
+func (m *Model) renderDiff(diff []output.DiffLine, width int) []string {
+	lines := []string{
+		"  " + m.styles.DiffDelete.Render("--- Expected"),
+		"  " + m.styles.DiffInsert.Render("+++ Actual"),
+	}
+
+	for _, line := range diff {
+		style := m.styles.ErrorDetail
+		switch line.Op {
+		case output.DiffDelete:
+			style = m.styles.DiffDelete
+		case output.DiffInsert:
+			style = m.styles.DiffInsert
+		case output.DiffHunk:
+			style = m.styles.DiffHunk
+		}
+
+		var b strings.Builder
+		b.WriteString(style.Render(line.Prefix()))
+		remaining := width - lipgloss.Width(line.Prefix())
+		for _, seg := range line.Segments {
+			if remaining <= 0 {
+				break
+			}
+			text := truncateWidth(seg.Text, remaining)
+			remaining -= lipgloss.Width(text)
+			if seg.Changed {
+				b.WriteString(style.Reverse(true).Render(text))
+			} else {
+				b.WriteString(style.Render(text))
+			}
+		}
+		lines = append(lines, "  "+b.String())
+	}
+
+	return lines
+}
+
 func (m *Model) renderHelpBar() string {
 	if m.copyNotice != "" {
-		return styles.TestPassed.Render(m.copyNotice)
+		return m.styles.TestPassed.Render(m.copyNotice)
 	}
 
+	k := m.keys
 	var help string
-	if m.phase == PhaseRunning {
-		help = "[Tab] Panel  [↑↓] Navigate  [Enter] Expand  [c] Copy  [Ctrl+C] Quit"
+	if m.showHelp {
+		help = hints(hint("Close", k.Help, k.Back))
+	} else if m.phase == PhaseExploring && m.treeSearching {
+		help = "Type to search  [Enter] Done  [Esc] Clear"
+	} else if m.viewingTimeline {
+		help = hints(hint("Worker", k.Up, k.Down), hint("Move cursor", k.Left, k.Right), hint("Back", k.Back), hint("Help", k.Help), hint("Quit", k.Quit))
+	} else if m.inspecting {
+		help = hints(hint("Worker", k.Left, k.Right), hint("Scroll output", k.Up, k.Down), hint("Back", k.Back), hint("Help", k.Help), "[Ctrl+C] Quit")
+	} else if m.errorSearching {
+		help = "Type to search  [Ctrl+R] Regex  [Enter] Done  [Esc] Clear"
+	} else if m.phase == PhaseExploring {
+		help = hints(hint("Navigate", k.Up, k.Down), hint("Collapse/Expand", k.Left, k.Right, k.Enter), hint("Filter", k.Filter), hint("Search", k.Search),
+			hint("Open", k.Open), hint("Re-run", k.Rerun, k.RerunAll), hint("Back", k.Back), hint("Help", k.Help), hint("Quit", k.Quit))
 	} else {
-		help = "[Tab] Panel  [↑↓] Navigate  [Enter] Expand  [c] Copy  [q] Quit"
+		help = hints(hint("Panel", k.Tab), m.panelHelp(), hint("Copy", k.Copy))
+		switch {
+		case m.phase == PhaseRunning && m.draining:
+			help = hints(help, hint("Help", k.Help), "[Ctrl+C] Quit now")
+		case m.phase == PhaseRunning:
+			help = hints(help, hint("Stop", k.Stop), hint("Help", k.Help), "[Ctrl+C] Quit")
+		default:
+			help = hints(help, hint("Explore", k.Explore), hint("Timeline", k.Timeline), hint("Help", k.Help), hint("Quit", k.Quit))
+		}
+	}
+	return m.styles.HelpBar.Render(m.styles.Icons.Text(help))
+}
+
+func (m *Model) panelHelp() string {
+	k := m.keys
+	switch m.activePanel {
+	case PanelWorkers:
+		return hints(hint("Select", k.Up, k.Down), hint("Inspect worker", k.Enter))
+	case PanelErrors:
+		help := hints(hint("Navigate", k.Up, k.Down), hint("Expand", k.Enter), hint("Search", k.Search), hint("Next/Prev", k.Next, k.Previous), hint("Group", k.Group))
+		if m.phase == PhaseComplete {
+			help = hints(help, hint("Open", k.Open), hint("Re-run", k.Rerun, k.RerunAll))
+		}
+		return help
+	}
+	return hints(hint("Navigate", k.Up, k.Down), hint("Expand", k.Enter))
+}
+
+// truncateWidth shortens text to at most width terminal cells, so that wide
+// and multibyte characters are never split.
+func truncateWidth(text string, width int) string {
+	if lipgloss.Width(text) <= width {
+		return text
+	}
+	ellipsis := "..."
+	if width <= len(ellipsis) {
+		ellipsis = ""
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range text {
+		w := lipgloss.Width(string(r))
+		if used+w > width-len(ellipsis) {
+			break
+		}
+		b.WriteRune(r)
+		used += w
 	}
-	return styles.HelpBar.Render(help)
+	return b.String() + ellipsis
 }
 
 func truncateName(name string, maxLen int) string {
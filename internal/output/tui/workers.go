@@ -0,0 +1,238 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// maxWorkerOutput is the number of raw output lines kept for each worker.
+const maxWorkerOutput = 1000
+
+type fileStatus int
+
+const (
+	filePending fileStatus = iota
+	fileRunning
+	fileDone
+)
+
+// sortedWorkers returns the worker IDs with finished workers last.
+func (m *Model) sortedWorkers() []int {
+	sorted := make([]int, 0, len(m.workerOrder))
+	var finished []int
+	for _, id := range m.workerOrder {
+		w := m.workers[id]
+		if w.HasTestCount && w.Completed >= w.Total {
+			finished = append(finished, id)
+		} else {
+			sorted = append(sorted, id)
+		}
+	}
+	return append(sorted, finished...)
+}
+
+func (m *Model) moveWorkerCursor(delta int) {
+	sorted := m.sortedWorkers()
+	if len(sorted) == 0 {
+		return
+	}
+	index := 0
+	for i, id := range sorted {
+		if id == m.workerCursor {
+			index = i
+		}
+	}
+	m.workerCursor = sorted[max(min(index+delta, len(sorted)-1), 0)]
+}
+
+// fileStatus reports whether the worker's i-th file has run, is running or is
+// still to run. Files are tracked by name as they need not run in the order
+// they were assigned.
+func (w *WorkerNode) fileStatus(i int) fileStatus {
+	switch {
+	case w.DoneFiles[w.Files[i]]:
+		return fileDone
+	case w.Files[i] == w.CurrentFile && !w.Finished:
+		return fileRunning
+	}
+	return filePending
+}
+
+// focusIndex returns the index of the running file, or of the first file not
+// yet done when none is running.
+func (w *WorkerNode) focusIndex() int {
+	for i, file := range w.Files {
+		if file == w.CurrentFile {
+			return i
+		}
+	}
+	for i, file := range w.Files {
+		if !w.DoneFiles[file] {
+			return i
+		}
+	}
+	return len(w.Files)
+}
+
+func (m *Model) handleInspectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	keys := m.keys
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		m.inspecting = false
+		return m.handleKeyPress(msg)
+
+	case key.Matches(msg, keys.Back), key.Matches(msg, keys.Enter):
+		m.inspecting = false
+
+	case key.Matches(msg, keys.Left):
+		m.moveWorkerCursor(-1)
+		m.inspectOffset = 0
+
+	case key.Matches(msg, keys.Right):
+		m.moveWorkerCursor(1)
+		m.inspectOffset = 0
+
+	case key.Matches(msg, keys.Up):
+		m.inspectOffset++
+
+	case key.Matches(msg, keys.Down):
+		m.inspectOffset = max(m.inspectOffset-1, 0)
+
+	case key.Matches(msg, keys.PageUp):
+		m.inspectOffset += 10
+
+	case key.Matches(msg, keys.PageDown):
+		m.inspectOffset = max(m.inspectOffset-10, 0)
+
+	case key.Matches(msg, keys.Stop):
+		if m.phase == PhaseRunning && !m.draining && m.onInterrupt != nil {
+			m.drain()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) renderWorkerDetail(height int) string {
+	w := m.workers[m.workerCursor]
+	if w == nil {
+		return ""
+	}
+
+	infoWidth := max(m.width*2/5, 30)
+	outputWidth := max(m.width-infoWidth-3, 20)
+
+	info := m.styles.Panel.Width(infoWidth).Height(height).Render(m.renderWorkerInfo(w, height, infoWidth-4))
+	out := m.styles.ActivePanel.Width(outputWidth).Height(height).Render(m.renderWorkerOutput(w, height, outputWidth-4))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, info, " ", out)
+}
+
+func (m *Model) renderWorkerInfo(w *WorkerNode, height, width int) string {
+	lines := []string{m.styles.Bold.Render(fmt.Sprintf("Worker %d of %d", w.ID+1, m.workerCount)), ""}
+	row := func(label, value string) {
+		lines = append(lines, m.styles.Dim.Render(label)+" "+truncateName(value, max(width-len(label)-1, 10)))
+	}
+
+	status := m.styles.TestRunning.Render("running")
+	switch {
+	case w.Err != nil:
+		status = m.styles.TestFailed.Render("failed")
+	case w.Finished:
+		status = m.styles.TestPassed.Render("finished")
+	case w.Process.PID == 0:
+		status = m.styles.Dim.Render("starting")
+	}
+	lines = append(lines, m.styles.Dim.Render("Status:")+" "+status)
+	if w.Err != nil {
+		for _, l := range wrapText(w.Err.Error(), width) {
+			lines = append(lines, m.styles.ErrorMsg.Render(l))
+		}
+	}
+	if w.Process.PID != 0 {
+		row("PID:", fmt.Sprintf("%d", w.Process.PID))
+	}
+	if w.Process.ConfigPath != "" {
+		row("Config:", w.Process.ConfigPath)
+	}
+	if w.PeakMemory > 0 {
+		row("Memory:", fmt.Sprintf("%s (peak %s)", output.FormatBytes(w.Memory), output.FormatBytes(w.PeakMemory)))
+		row("CPU:", formatDuration(w.CPUTime))
+	}
+	if w.Process.Command != "" {
+		lines = append(lines, m.styles.Dim.Render("Command:"))
+		for _, l := range wrapText(w.Process.Command, width) {
+			lines = append(lines, l)
+		}
+	}
+
+	if len(w.Hooks) > 0 {
+		lines = append(lines, "", m.styles.Bold.Render("Hooks"))
+		for _, h := range w.Hooks {
+			result := m.styles.TestPassed.Render("ok")
+			if h.Err != nil {
+				result = m.styles.TestFailed.Render(h.Err.Error())
+			}
+			lines = append(lines, fmt.Sprintf("%s %s %s", h.Hook, m.styles.Dim.Render(formatDuration(h.Duration)), result))
+		}
+	}
+
+	var done, running int
+	for i := range w.Files {
+		switch w.fileStatus(i) {
+		case fileDone:
+			done++
+		case fileRunning:
+			running++
+		}
+	}
+	lines = append(lines, "", m.styles.Bold.Render(fmt.Sprintf("Files (%d done, %d running, %d pending)", done, running, len(w.Files)-done-running)))
+
+	// Keep the running file in view, with the files done before it above
+	available := max(height-len(lines), 1)
+	start := max(min(w.focusIndex()-available/2, len(w.Files)-available), 0)
+	for i := start; i < min(start+available, len(w.Files)); i++ {
+		var icon string
+		style := m.styles.Dim
+		switch w.fileStatus(i) {
+		case fileDone:
+			icon, style = m.styles.Icons.Passed, m.styles.TestPassed
+		case fileRunning:
+			icon, style = m.styles.Icons.Running, m.styles.TestRunning
+		default:
+			icon = m.styles.Icons.Pending
+		}
+		lines = append(lines, style.Render(icon)+" "+style.Render(truncateName(w.Files[i], max(width-2, 10))))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) renderWorkerOutput(w *WorkerNode, height, width int) string {
+	title := "Output"
+	if m.inspectOffset > 0 {
+		title += m.styles.Dim.Render(fmt.Sprintf("  (%d lines up)", m.inspectOffset))
+	}
+	lines := []string{m.styles.Bold.Render(title), ""}
+
+	if len(w.Output) == 0 {
+		lines = append(lines, m.styles.Dim.Render("No output yet"))
+		return strings.Join(lines, "\n")
+	}
+
+	visible := max(height-2, 1)
+	m.inspectOffset = min(m.inspectOffset, max(len(w.Output)-visible, 0))
+	end := len(w.Output) - m.inspectOffset
+	for _, l := range w.Output[max(end-visible, 0):end] {
+		lines = append(lines, m.styles.ErrorDetail.Render(truncateName(l, width)))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,351 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+	"github.com/alexdempster44/phpunit-parallel/internal/history"
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+var ErrNotReproduced = errors.New("failure not reproduced")
+
+type BisectResult struct {
+	Test     string
+	File     string
+	Replayed int
+	// Polluters is the smallest set of preceding files found that still makes
+	// the test fail. It is empty when the test fails on its own.
+	Polluters []string
+}
+
+// Bisect replays the file order of the worker that last ran the failing test
+// and narrows the files that ran before it down to those that make it fail.
+func (r *Runner) Bisect(test string, progress io.Writer) (*BisectResult, error) {
+	runs, err := history.NewStore(r.RunnerConfig.ConfigBuildDir, 0).Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+
+	run, result, ok := history.LastFailure(runs, test)
+	if !ok {
+		return nil, fmt.Errorf("no recorded failure of %s", test)
+	}
+
+	order := run.Files[result.Worker]
+	index := -1
+	for i, file := range order {
+		if file == result.File {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("file order of worker %d was not recorded for %s", result.Worker+1, result.File)
+	}
+
+	hookTimeout, err := r.RunnerConfig.HookTimeoutDuration()
+	if err != nil {
+		return nil, err
+	}
+	shutdownGrace, err := r.RunnerConfig.ShutdownGraceDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bisector{
+		runner:             r,
+		test:               result.Name,
+		file:               result.File,
+		suites:             r.fileSuites(),
+		hookTimeout:        hookTimeout,
+		maxFilesPerProcess: run.MaxFilesPerProcess,
+		progress:           progress,
+	}
+	stopSignals := b.handleSignals(shutdownGrace)
+	defer stopSignals()
+	res := &BisectResult{
+		Test: result.Name,
+		File: result.File,
+	}
+	predecessors := order[:index]
+	res.Replayed = len(predecessors)
+
+	_, _ = fmt.Fprintf(progress, "Replaying %d file(s) run by worker %d before %s\n", len(predecessors), result.Worker+1, result.File)
+	fails, err := b.trial(predecessors)
+	if err != nil {
+		return nil, err
+	}
+	if !fails {
+		return nil, ErrNotReproduced
+	}
+
+	fails, err = b.trial(nil)
+	if err != nil {
+		return nil, err
+	}
+	if fails {
+		return res, nil
+	}
+
+	var trialErr error
+	res.Polluters = ddmin(predecessors, func(files []string) bool {
+		if trialErr != nil {
+			return false
+		}
+		fails, err := b.trial(files)
+		if err != nil {
+			trialErr = err
+		}
+		return fails
+	})
+	if trialErr != nil {
+		return nil, trialErr
+	}
+
+	return res, nil
+}
+
+// ddmin reduces files to a minimal subset for which fails still holds, using
+// delta debugging so that polluters which only fail together are found.
+func ddmin(files []string, fails func([]string) bool) []string {
+	n := 2
+	for len(files) >= 2 {
+		chunks := splitChunks(files, n)
+		reduced := false
+
+		for _, chunk := range chunks {
+			if fails(chunk) {
+				files, n, reduced = chunk, 2, true
+				break
+			}
+		}
+
+		if !reduced && n > 2 {
+			for i := range chunks {
+				var complement []string
+				for j, chunk := range chunks {
+					if j != i {
+						complement = append(complement, chunk...)
+					}
+				}
+				if fails(complement) {
+					files, n, reduced = complement, max(n-1, 2), true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if n >= len(files) {
+				break
+			}
+			n = min(n*2, len(files))
+		}
+	}
+	return files
+}
+
+func splitChunks(files []string, n int) [][]string {
+	var chunks [][]string
+	start := 0
+	for i := range n {
+		end := start + (len(files)-start)/(n-i)
+		chunks = append(chunks, files[start:end])
+		start = end
+	}
+	return chunks
+}
+
+type bisector struct {
+	runner             *Runner
+	test               string
+	file               string
+	suites             map[string]string
+	hookTimeout        time.Duration
+	maxFilesPerProcess int
+	progress           io.Writer
+
+	mu        sync.Mutex
+	worker    *Worker
+	cancelled bool
+}
+
+// handleSignals stops the running trial on SIGINT or SIGTERM by sending
+// SIGTERM to its process group, escalating to SIGKILL on a second signal or
+// once the grace period expires. The returned function stops listening.
+func (b *bisector) handleSignals(grace time.Duration) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if b.cancel(syscall.SIGTERM) {
+					time.AfterFunc(grace, func() { b.cancel(syscall.SIGKILL) })
+				} else {
+					b.cancel(syscall.SIGKILL)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// cancel sends sig to the running trial and prevents further trials from
+// starting. It reports whether this was the first cancellation.
+func (b *bisector) cancel(sig syscall.Signal) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	first := !b.cancelled
+	b.cancelled = true
+	if b.worker != nil {
+		b.worker.Signal(sig)
+	}
+	return first
+}
+
+// setWorker makes w the worker that cancel signals, failing once the bisect
+// has been cancelled.
+func (b *bisector) setWorker(w *Worker) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.worker = w
+	if b.cancelled {
+		return ErrCancelled
+	}
+	return nil
+}
+
+// trial runs the given files followed by the failing test's file in a single
+// PHPUnit process and reports whether the test failed.
+func (b *bisector) trial(files []string) (bool, error) {
+	r := b.runner
+
+	var tests []distributor.TestFile
+	for _, f := range append(append([]string{}, files...), b.file) {
+		tests = append(tests, distributor.TestFile{
+			Path:  filepath.Join(r.BaseDir, f),
+			Suite: b.suites[f],
+		})
+	}
+
+	out := &bisectOutput{test: b.test, testNames: make(map[string]string)}
+	// Trials run every test of each file in the given order, recycling
+	// processes as the recorded run did so that its process boundaries hold
+	cfg := r.workerConfig(b.hookTimeout)
+	cfg.Filter, cfg.Group, cfg.ExcludeGroup = "", "", ""
+	cfg.MaxFilesPerProcess = b.maxFilesPerProcess
+	cfg.LogName = "bisect.log"
+	w := NewWorker(0, tests, cfg, out)
+	w.WorkerCount = 1
+	if err := b.setWorker(w); err != nil {
+		return false, err
+	}
+
+	runErr := w.Run()
+	w.runAfterWorker()
+	if err := b.setWorker(nil); err != nil {
+		return false, err
+	}
+	if out.hookErr != nil {
+		return false, out.hookErr
+	}
+	if !out.ran {
+		return false, fmt.Errorf("%s did not run: %v", b.test, runErr)
+	}
+
+	status := "passes"
+	if out.failed {
+		status = "fails"
+	}
+	_, _ = fmt.Fprintf(b.progress, "  %3d preceding file(s): %s\n", len(files), status)
+
+	return out.failed, nil
+}
+
+// fileSuites maps the project-relative path of each test file to its suite.
+func (r *Runner) fileSuites() map[string]string {
+	suites := make(map[string]string)
+	tests, err := r.discoverTests()
+	if err != nil {
+		return suites
+	}
+	for _, t := range tests {
+		if rel, err := filepath.Rel(r.BaseDir, t.Path); err == nil {
+			suites[rel] = t.Suite
+		}
+	}
+	return suites
+}
+
+// bisectOutput watches the output of a trial for the result of one test.
+type bisectOutput struct {
+	mu        sync.Mutex
+	test      string
+	testNames map[string]string
+	ran       bool
+	failed    bool
+	hookErr   error
+}
+
+func (o *bisectOutput) Start(opts output.StartOptions)                         {}
+func (o *bisectOutput) WorkerStart(workerID, testCount int)                    {}
+func (o *bisectOutput) WorkerBatch(workerID, fileCount int)                    {}
+func (o *bisectOutput) WorkerProcess(workerID int, process output.ProcessInfo) {}
+
+func (o *bisectOutput) WorkerHook(workerID int, result output.HookResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if result.Err != nil && o.hookErr == nil {
+		o.hookErr = fmt.Errorf("%s failed: %w", result.Hook, result.Err)
+	}
+}
+
+func (o *bisectOutput) WorkerLine(workerID int, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name := output.ParseTeamCityAttr(line, "name")
+	switch {
+	case strings.HasPrefix(line, "##teamcity[testStarted "):
+		testName := strings.TrimPrefix(output.ParseTeamCityTestName(line), `\`)
+		o.testNames[name] = testName
+		if testName == o.test {
+			o.ran = true
+		}
+
+	case strings.HasPrefix(line, "##teamcity[testFailed "):
+		if o.testNames[name] == o.test {
+			o.failed = true
+		}
+	}
+}
+
+func (o *bisectOutput) WorkerUsage(workerID int, usage output.ResourceUsage) {}
+func (o *bisectOutput) WorkerComplete(workerID int, err error)               {}
+func (o *bisectOutput) CleanupProgress(completed, total int)                 {}
+func (o *bisectOutput) Finish()                                              {}
+func (o *bisectOutput) SetOnCancel(fn func())                                {}
+func (o *bisectOutput) Draining()                                            {}
+func (o *bisectOutput) Cancel()                                              {}
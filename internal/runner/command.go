@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// command builds the PHPUnit invocation for the worker. RunWorkerArgs is used
+// as an argv list with no shell involved; otherwise RunWorker is treated as a
+// shell template when it contains spaces or placeholders. In both forms {}
+// marks where the runner's arguments go, and they are appended when absent.
+func (w *Worker) command(configPath string, tests []distributor.TestFile, args []string) *exec.Cmd {
+	files := w.relativeTestPaths(tests)
+
+	if len(w.RunWorkerArgs) > 0 {
+		argv := expandArgv(w.RunWorkerArgs, map[string][]string{
+			"{}":       args,
+			"{config}": {configPath},
+			"{worker}": {strconv.Itoa(w.ID)},
+			"{files}":  files,
+		})
+		if !containsPlaceholder(w.RunWorkerArgs, "{}") {
+			argv = append(argv, args...)
+		}
+		return exec.Command(argv[0], argv[1:]...)
+	}
+
+	if !strings.Contains(w.RunWorker, " ") && !strings.Contains(w.RunWorker, "{") {
+		return exec.Command(w.RunWorker, args...)
+	}
+
+	script := strings.NewReplacer(
+		"{}", output.ShellJoin(args),
+		"{config}", output.ShellQuote(configPath),
+		"{worker}", strconv.Itoa(w.ID),
+		"{files}", output.ShellJoin(files),
+	).Replace(w.RunWorker)
+	if !strings.Contains(w.RunWorker, "{}") {
+		script += " " + output.ShellJoin(args)
+	}
+	return exec.Command("sh", "-c", script)
+}
+
+func (w *Worker) relativeTestPaths(tests []distributor.TestFile) []string {
+	paths := make([]string, len(tests))
+	for i, test := range tests {
+		rel, err := filepath.Rel(w.BaseDir, test.Path)
+		if err != nil {
+			rel = test.Path
+		}
+		paths[i] = rel
+	}
+	return paths
+}
+
+// expandArgv substitutes placeholders in each argument. An argument that is
+// exactly a placeholder expands to all of its values as separate arguments.
+func expandArgv(template []string, placeholders map[string][]string) []string {
+	pairs := make([]string, 0, len(placeholders)*2)
+	for name, values := range placeholders {
+		pairs = append(pairs, name, strings.Join(values, " "))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	var argv []string
+	for _, arg := range template {
+		if values, ok := placeholders[arg]; ok {
+			argv = append(argv, values...)
+			continue
+		}
+		argv = append(argv, replacer.Replace(arg))
+	}
+	return argv
+}
+
+func containsPlaceholder(args []string, placeholder string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, placeholder) {
+			return true
+		}
+	}
+	return false
+}
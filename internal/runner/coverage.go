@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+func (r *Runner) coverageDir() string {
+	return filepath.Join(r.RunnerConfig.ConfigBuildDir, "coverage")
+}
+
+func (r *Runner) prepareCoverageDir() error {
+	dir := r.coverageDir()
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// mergeCoverage combines the PHP coverage files written by each worker into
+// the requested reports using phpcov. Its output is returned rather than
+// printed, so it can be shown once the output has finished.
+func (r *Runner) mergeCoverage() ([]byte, error) {
+	cov := r.RunnerConfig.Coverage
+
+	args := []string{"merge"}
+	if cov.Clover != "" {
+		args = append(args, "--clover", cov.Clover)
+	}
+	if cov.HTML != "" {
+		args = append(args, "--html", cov.HTML)
+	}
+	if cov.Cobertura != "" {
+		args = append(args, "--cobertura", cov.Cobertura)
+	}
+	if cov.Text != "" {
+		args = append(args, "--text", cov.Text)
+	}
+	args = append(args, r.coverageDir())
+
+	var cmd *exec.Cmd
+	if strings.Contains(cov.PHPCov, " ") {
+		cmd = exec.Command("sh", "-c", cov.PHPCov+" "+output.ShellJoin(args))
+	} else {
+		cmd = exec.Command(cov.PHPCov, args...)
+	}
+	cmd.Dir = r.BaseDir
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		return buf.Bytes(), fmt.Errorf("%s: %w", cov.PHPCov, err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var envPlaceholderPattern = regexp.MustCompile(`\{(\w+)(?::([^}]*))?\}`)
+
+var (
+	allocatedPortsMu sync.Mutex
+	allocatedPorts   = make(map[int]bool)
+)
+
+// expandEnv resolves the configured environment templates for this worker.
+// Each {port} placeholder is allocated once, so hooks and PHPUnit see the same
+// value. Unknown placeholders, such as the braces of ${HOME}, are left as
+// written.
+func (w *Worker) expandEnv() ([]string, error) {
+	env := make([]string, 0, len(w.Env))
+	for _, v := range w.Env {
+		var expandErr error
+		value := envPlaceholderPattern.ReplaceAllStringFunc(v.Value, func(match string) string {
+			parts := envPlaceholderPattern.FindStringSubmatch(match)
+			resolved, ok, err := w.resolvePlaceholder(parts[1], parts[2])
+			if !ok {
+				return match
+			}
+			if err != nil && expandErr == nil {
+				expandErr = fmt.Errorf("%s: %w", v.Name, err)
+			}
+			return resolved
+		})
+		if expandErr != nil {
+			return nil, expandErr
+		}
+		env = append(env, v.Name+"="+value)
+	}
+	return env, nil
+}
+
+// resolvePlaceholder returns the value of the named placeholder, and false
+// when there is no placeholder by that name.
+func (w *Worker) resolvePlaceholder(name, arg string) (string, bool, error) {
+	switch name {
+	case "worker":
+		return strconv.Itoa(w.ID), true, nil
+	case "worker1":
+		return strconv.Itoa(w.ID + 1), true, nil
+	case "runner_pid":
+		return strconv.Itoa(os.Getpid()), true, nil
+	case "project":
+		return filepath.Base(w.BaseDir), true, nil
+	case "port":
+		if arg == "" {
+			port, err := allocatePort()
+			if err != nil {
+				return "", true, fmt.Errorf("failed to allocate port: %w", err)
+			}
+			return strconv.Itoa(port), true, nil
+		}
+		base, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", true, fmt.Errorf("invalid port base %q", arg)
+		}
+		return strconv.Itoa(base + w.ID), true, nil
+	}
+	return "", false, nil
+}
+
+// allocatePort asks the OS for a free TCP port, skipping any already handed to
+// another worker during this run.
+func allocatePort() (int, error) {
+	allocatedPortsMu.Lock()
+	defer allocatedPortsMu.Unlock()
+
+	for range 10 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		_ = l.Close()
+
+		if !allocatedPorts[port] {
+			allocatedPorts[port] = true
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found")
+}
@@ -0,0 +1,263 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+)
+
+var (
+	namespacePattern     = regexp.MustCompile(`^namespace\s+([\w\\]+)\s*[;{]`)
+	classPattern         = regexp.MustCompile(`^(?:(?:abstract|final|readonly)\s+)*class\s+(\w+)`)
+	extendsPattern       = regexp.MustCompile(`\bextends\s+([\w\\]+)`)
+	traitUsePattern      = regexp.MustCompile(`^use\s+[\w\\]`)
+	functionPattern      = regexp.MustCompile(`^(?:(?:public|protected|private|static|final|abstract)\s+)*function\s+&?\s*(\w+)\s*\(`)
+	groupAttrPattern     = regexp.MustCompile(`\b(Group|Ticket)\(\s*['"]([^'"]+)['"]\s*,?\s*\)`)
+	sizeAttrPattern      = regexp.MustCompile(`\b(Small|Medium|Large)\b`)
+	testAttrPattern      = regexp.MustCompile(`\bTest\b`)
+	groupAnnotationRegex = regexp.MustCompile(`@(group|ticket)\s+(\S+)`)
+	sizeAnnotationRegex  = regexp.MustCompile(`@(small|medium|large)\b`)
+	testAnnotationRegex  = regexp.MustCompile(`@test\b`)
+)
+
+type testMethod struct {
+	Name   string
+	Groups []string
+}
+
+type testFileInfo struct {
+	ClassName   string
+	ClassGroups []string
+	Methods     []testMethod
+	// Inherits is set when the class extends something other than TestCase
+	// or uses a trait, either of which can add groups and test methods that
+	// aren't visible in the file.
+	Inherits bool
+}
+
+// scanTestFile statically extracts the test class, its test methods and the
+// groups declared on each via attributes or docblock annotations.
+func scanTestFile(path string) (*testFileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info := &testFileInfo{}
+	var namespace string
+	var pendingGroups []string
+	pendingTest := false
+	// attribute collects the lines of an attribute until its brackets close
+	var attribute string
+	inClassHeader := false
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if attribute != "" || strings.HasPrefix(line, "#[") {
+			attribute += line + " "
+			if strings.Count(attribute, "[") > strings.Count(attribute, "]") {
+				continue
+			}
+			line, attribute = attribute, ""
+			for _, m := range groupAttrPattern.FindAllStringSubmatch(line, -1) {
+				pendingGroups = append(pendingGroups, m[2])
+			}
+			for _, m := range sizeAttrPattern.FindAllStringSubmatch(line, -1) {
+				pendingGroups = append(pendingGroups, strings.ToLower(m[1]))
+			}
+			if testAttrPattern.MatchString(line) {
+				pendingTest = true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "/**") || strings.HasPrefix(line, "*") {
+			for _, m := range groupAnnotationRegex.FindAllStringSubmatch(line, -1) {
+				pendingGroups = append(pendingGroups, m[2])
+			}
+			for _, m := range sizeAnnotationRegex.FindAllStringSubmatch(line, -1) {
+				pendingGroups = append(pendingGroups, m[1])
+			}
+			if testAnnotationRegex.MatchString(line) {
+				pendingTest = true
+			}
+			continue
+		}
+
+		if m := namespacePattern.FindStringSubmatch(line); m != nil {
+			namespace = m[1]
+			continue
+		}
+
+		if m := classPattern.FindStringSubmatch(line); m != nil && info.ClassName == "" {
+			info.ClassName = m[1]
+			if namespace != "" {
+				info.ClassName = namespace + `\` + m[1]
+			}
+			info.ClassGroups = pendingGroups
+			pendingGroups = nil
+			pendingTest = false
+			inClassHeader = true
+		}
+
+		if inClassHeader {
+			if m := extendsPattern.FindStringSubmatch(line); m != nil {
+				parent := m[1][strings.LastIndex(m[1], `\`)+1:]
+				if parent != "TestCase" {
+					info.Inherits = true
+				}
+			}
+			inClassHeader = !strings.Contains(line, "{")
+			continue
+		}
+
+		if info.ClassName != "" && traitUsePattern.MatchString(line) {
+			info.Inherits = true
+			continue
+		}
+
+		if m := functionPattern.FindStringSubmatch(line); m != nil {
+			if pendingTest || strings.HasPrefix(m[1], "test") {
+				info.Methods = append(info.Methods, testMethod{Name: m[1], Groups: pendingGroups})
+			}
+			pendingGroups = nil
+			pendingTest = false
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, "//") {
+			pendingGroups = nil
+			pendingTest = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+type testFilter struct {
+	include []string
+	exclude []string
+	pattern *regexp.Regexp
+}
+
+func newTestFilter(filter, group, excludeGroup string) *testFilter {
+	f := &testFilter{
+		include: splitGroups(group),
+		exclude: splitGroups(excludeGroup),
+		pattern: compileFilter(filter),
+	}
+	if len(f.include) == 0 && len(f.exclude) == 0 && f.pattern == nil {
+		return nil
+	}
+	return f
+}
+
+func splitGroups(value string) []string {
+	var groups []string
+	for g := range strings.SplitSeq(value, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// compileFilter mirrors PHPUnit's handling of --filter closely enough to rule
+// out files. Filters that target data sets, or that Go cannot compile, return
+// nil so that no file is excluded on their account.
+func compileFilter(filter string) *regexp.Regexp {
+	if filter == "" || strings.Contains(filter, "data set") || strings.ContainsAny(filter, "#@") {
+		return nil
+	}
+
+	pattern := "(?i)" + filter
+	if len(filter) > 2 && filter[0] == '/' {
+		if end := strings.LastIndex(filter, "/"); end > 0 {
+			pattern = filter[1:end]
+			if strings.Contains(filter[end+1:], "i") {
+				pattern = "(?i)" + pattern
+			}
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// matches reports whether a file may contain tests selected by the filter.
+// Files that cannot be understood statically, including those whose class
+// inherits from a parent class or trait, are always kept.
+func (f *testFilter) matches(info *testFileInfo) bool {
+	if info.ClassName == "" || len(info.Methods) == 0 || info.Inherits {
+		return true
+	}
+
+	if hasAny(info.ClassGroups, f.exclude) {
+		return false
+	}
+
+	for _, method := range info.Methods {
+		groups := append(append([]string{}, info.ClassGroups...), method.Groups...)
+		if len(groups) == 0 {
+			groups = []string{"default"}
+		}
+
+		if len(f.include) > 0 && !hasAny(groups, f.include) {
+			continue
+		}
+		if hasAny(groups, f.exclude) {
+			continue
+		}
+		if f.pattern != nil && !f.pattern.MatchString(info.ClassName+"::"+method.Name) {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+func hasAny(groups, wanted []string) bool {
+	for _, g := range groups {
+		for _, w := range wanted {
+			if g == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Runner) filterTests(tests []distributor.TestFile) []distributor.TestFile {
+	if !r.RunnerConfig.StaticFilter {
+		return tests
+	}
+
+	f := newTestFilter(r.RunnerConfig.Filter, r.RunnerConfig.Group, r.RunnerConfig.ExcludeGroup)
+	if f == nil {
+		return tests
+	}
+
+	filtered := make([]distributor.TestFile, 0, len(tests))
+	for _, test := range tests {
+		info, err := scanTestFile(test.Path)
+		if err != nil || f.matches(info) {
+			filtered = append(filtered, test)
+		}
+	}
+	return filtered
+}
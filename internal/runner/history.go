@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"path/filepath"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+	"github.com/alexdempster44/phpunit-parallel/internal/history"
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// recordingOutput passes worker output on to the recorder as well as the
+// configured output.
+type recordingOutput struct {
+	output.Output
+	recorder *history.Recorder
+}
+
+func (o recordingOutput) WorkerLine(workerID int, line string) {
+	o.recorder.Line(workerID, line)
+	o.Output.WorkerLine(workerID, line)
+}
+
+func (r *Runner) newRecorder(tests []distributor.TestFile) *history.Recorder {
+	if r.RunnerConfig.HistorySize <= 0 {
+		return nil
+	}
+
+	baseDir, err := filepath.Abs(r.BaseDir)
+	if err != nil {
+		return nil
+	}
+
+	suites := make(map[string]string, len(tests))
+	for _, test := range tests {
+		if path, err := filepath.Abs(test.Path); err == nil {
+			suites[path] = test.Suite
+		}
+	}
+
+	return history.NewRecorder(baseDir, suites)
+}
+
+func (r *Runner) saveHistory(recorder *history.Recorder, workerCount int) {
+	store := history.NewStore(r.RunnerConfig.ConfigBuildDir, r.RunnerConfig.HistorySize)
+	run := recorder.Run(workerCount, r.seed())
+	run.MaxFilesPerProcess = r.RunnerConfig.MaxFilesPerProcess
+	_ = store.Append(run)
+}
+
+// loadHistory returns the recorded runs, or nil when history is disabled or
+// can't be read.
+func (r *Runner) loadHistory() []history.Run {
+	if r.RunnerConfig.HistorySize <= 0 {
+		return nil
+	}
+	runs, err := history.NewStore(r.RunnerConfig.ConfigBuildDir, r.RunnerConfig.HistorySize).Load()
+	if err != nil {
+		return nil
+	}
+	return runs
+}
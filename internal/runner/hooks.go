@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+const hookTailLines = 20
+
+// runHook runs a worker hook in its own process group, capturing its output
+// into the worker's log and reporting the result to the output. Cancellable
+// hooks are tracked as the worker's current process so that Signal reaches
+// them; after-worker hooks run during cleanup and are not.
+func (w *Worker) runHook(name, command string, cancellable bool) error {
+	ctx := context.Background()
+	if w.HookTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.HookTimeout)
+		defer cancel()
+	}
+
+	var buf bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = w.BaseDir
+	cmd.Env = w.env()
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	var err error
+	if cancellable {
+		if err = w.start(cmd); err == nil {
+			err = cmd.Wait()
+			w.release()
+		}
+	} else {
+		err = cmd.Run()
+	}
+	duration := time.Since(start)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", w.HookTimeout)
+	}
+
+	logPath, logErr := w.appendLog(name, buf.Bytes(), duration, err)
+	if logErr != nil {
+		logPath = ""
+	}
+
+	w.Output.WorkerHook(w.ID, output.HookResult{
+		Hook:     name,
+		Duration: duration,
+		Output:   tail(buf.String(), hookTailLines),
+		LogPath:  logPath,
+		Err:      err,
+	})
+
+	return err
+}
+
+func (w *Worker) logPath() string {
+	if w.LogName != "" {
+		return filepath.Join(w.ConfigBuildDir, w.LogName)
+	}
+	return filepath.Join(w.ConfigBuildDir, fmt.Sprintf("worker-%d.log", w.ID))
+}
+
+func (w *Worker) appendLog(name string, data []byte, duration time.Duration, err error) (string, error) {
+	if err := os.MkdirAll(w.ConfigBuildDir, 0755); err != nil {
+		return "", err
+	}
+
+	path := w.logPath()
+	f, openErr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if openErr != nil {
+		return "", openErr
+	}
+	defer func() { _ = f.Close() }()
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	if _, err := fmt.Fprintf(f, "==> %s (%s, %s)\n", name, status, duration.Round(time.Millisecond)); err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		_, _ = f.WriteString("\n")
+	}
+
+	return path, nil
+}
+
+func tail(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+// Rerun runs a single test again in a fresh worker once the run has finished.
+// Reruns borrow the IDs of the original workers so that per-worker resources
+// such as databases are never shared by two processes.
+func (r *Runner) Rerun(test output.RerunRequest) (output.RerunResult, error) {
+	hookTimeout, err := r.RunnerConfig.HookTimeoutDuration()
+	if err != nil {
+		return output.RerunResult{}, err
+	}
+
+	id := <-r.rerunIDs
+	defer func() { r.rerunIDs <- id }()
+
+	file := test.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(r.BaseDir, file)
+	}
+
+	out := &rerunOutput{key: test.Key}
+	cfg := r.workerConfig(hookTimeout)
+	cfg.Filter = "::" + regexp.QuoteMeta(test.Key) + "$"
+	cfg.Group, cfg.ExcludeGroup = "", ""
+	cfg.MaxFilesPerProcess = 0
+	// Keep the hook log of the original worker that failed
+	cfg.LogName = fmt.Sprintf("rerun-%d.log", id)
+	w := NewWorker(id, []distributor.TestFile{{Path: file, Suite: test.Suite}}, cfg, out)
+	w.WorkerCount = len(r.workers)
+	if !r.trackRerun(w) {
+		return output.RerunResult{}, fmt.Errorf("%s did not run: the run has finished", test.Name)
+	}
+	defer r.untrackRerun(w)
+
+	runErr := w.Run()
+	w.runAfterWorker()
+	if out.hookErr != nil {
+		return output.RerunResult{}, out.hookErr
+	}
+	if !out.ran {
+		if runErr != nil {
+			return output.RerunResult{}, fmt.Errorf("%s did not run: %w", test.Name, runErr)
+		}
+		return output.RerunResult{}, fmt.Errorf("%s did not run", test.Name)
+	}
+	if !out.finished {
+		if runErr != nil {
+			return output.RerunResult{}, fmt.Errorf("%s did not finish: %w", test.Name, runErr)
+		}
+		return output.RerunResult{}, fmt.Errorf("%s did not finish", test.Name)
+	}
+	return out.result, nil
+}
+
+// trackRerun registers a rerun's worker so that it can be stopped when the
+// output finishes. It reports false once reruns have been stopped.
+func (r *Runner) trackRerun(w *Worker) bool {
+	r.rerunMu.Lock()
+	defer r.rerunMu.Unlock()
+
+	if r.rerunsDone {
+		return false
+	}
+	if r.reruns == nil {
+		r.reruns = make(map[*Worker]struct{})
+	}
+	r.reruns[w] = struct{}{}
+	r.rerunWG.Add(1)
+	return true
+}
+
+func (r *Runner) untrackRerun(w *Worker) {
+	r.rerunMu.Lock()
+	delete(r.reruns, w)
+	r.rerunMu.Unlock()
+	r.rerunWG.Done()
+}
+
+// stopReruns terminates reruns still going when the output finishes, such as
+// when quitting the terminal UI mid-rerun, and waits for their after-worker
+// hooks. Processes still running after the grace period are killed.
+func (r *Runner) stopReruns(grace time.Duration) {
+	signal := func(sig syscall.Signal) {
+		r.rerunMu.Lock()
+		defer r.rerunMu.Unlock()
+		for w := range r.reruns {
+			w.Signal(sig)
+		}
+	}
+
+	r.rerunMu.Lock()
+	r.rerunsDone = true
+	r.rerunMu.Unlock()
+	signal(syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		r.rerunWG.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		signal(syscall.SIGKILL)
+		<-done
+	}
+}
+
+// rerunIDPool returns a channel holding the given worker IDs.
+func rerunIDPool(workers []*Worker) chan int {
+	ids := make(chan int, max(len(workers), 1))
+	for _, w := range workers {
+		ids <- w.ID
+	}
+	if len(workers) == 0 {
+		ids <- 0
+	}
+	return ids
+}
+
+// rerunOutput collects the result of the re-run test.
+type rerunOutput struct {
+	mu       sync.Mutex
+	key      string
+	ran      bool
+	finished bool
+	result   output.RerunResult
+	hookErr  error
+}
+
+func (o *rerunOutput) Start(opts output.StartOptions)                         {}
+func (o *rerunOutput) WorkerStart(workerID, testCount int)                    {}
+func (o *rerunOutput) WorkerBatch(workerID, fileCount int)                    {}
+func (o *rerunOutput) WorkerProcess(workerID int, process output.ProcessInfo) {}
+
+func (o *rerunOutput) WorkerHook(workerID int, result output.HookResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if result.Err != nil && o.hookErr == nil {
+		o.hookErr = fmt.Errorf("%s failed: %w", result.Hook, result.Err)
+	}
+}
+
+func (o *rerunOutput) WorkerLine(workerID int, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if output.ParseTeamCityAttr(line, "name") != o.key {
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(line, "##teamcity[testStarted "):
+		o.ran = true
+
+	case strings.HasPrefix(line, "##teamcity[testFailed "):
+		_, message, details := output.ParseTeamCityError(line)
+		expected, actual, comparison := output.ParseTeamCityComparison(line)
+		o.result.Failed = true
+		o.result.Message = message
+		o.result.Details = details
+		o.result.Expected = expected
+		o.result.Actual = actual
+		o.result.Comparison = comparison
+
+	case strings.HasPrefix(line, "##teamcity[testIgnored "):
+		o.result.Skipped = true
+		o.result.Message = output.ParseTeamCityAttr(line, "message")
+
+	case strings.HasPrefix(line, "##teamcity[testFinished "):
+		o.finished = true
+		ms, _ := strconv.Atoi(output.ParseTeamCityAttr(line, "duration"))
+		o.result.Duration = time.Duration(ms) * time.Millisecond
+	}
+}
+
+func (o *rerunOutput) WorkerUsage(workerID int, usage output.ResourceUsage) {}
+func (o *rerunOutput) WorkerComplete(workerID int, err error)               {}
+func (o *rerunOutput) CleanupProgress(completed, total int)                 {}
+func (o *rerunOutput) Finish()                                              {}
+func (o *rerunOutput) SetOnCancel(fn func())                                {}
+func (o *rerunOutput) Draining()                                            {}
+func (o *rerunOutput) Cancel()                                              {}
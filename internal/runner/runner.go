@@ -1,26 +1,44 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"syscall"
+	"time"
 
 	"github.com/alexdempster44/phpunit-parallel/internal/config"
 	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
+	"github.com/alexdempster44/phpunit-parallel/internal/history"
 	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
 )
 
+var ErrCancelled = errors.New("run cancelled")
+
 type Runner struct {
 	PHPUnitConfig *config.PHPUnit
 	RunnerConfig  *config.Runner
 	BaseDir       string
 	Output        output.Output
+
+	workers     []*Worker
+	workersDone chan struct{}
+	rerunIDs    chan int
+	rerunMu     sync.Mutex
+	reruns      map[*Worker]struct{}
+	rerunWG     sync.WaitGroup
+	rerunsDone  bool
+	cancelOnce  sync.Once
+	cancelled   atomic.Bool
+	draining    atomic.Bool
+	cleaningUp  atomic.Bool
 }
 
 func New(phpunitConfig *config.PHPUnit, runnerConfig *config.Runner, baseDir string, out output.Output) *Runner {
@@ -29,21 +47,62 @@ func New(phpunitConfig *config.PHPUnit, runnerConfig *config.Runner, baseDir str
 		RunnerConfig:  runnerConfig,
 		BaseDir:       baseDir,
 		Output:        out,
+		workersDone:   make(chan struct{}),
 	}
 }
 
 func (r *Runner) Run() error {
+	hookTimeout, err := r.RunnerConfig.HookTimeoutDuration()
+	if err != nil {
+		return err
+	}
+	shutdownGrace, err := r.RunnerConfig.ShutdownGraceDuration()
+	if err != nil {
+		return err
+	}
+
 	tests, err := r.discoverTests()
 	if err != nil {
 		return fmt.Errorf("failed to discover tests: %w", err)
 	}
 
-	dist := distributor.RoundRobin(tests, r.RunnerConfig.Workers)
-	workers := r.createWorkers(dist)
-	workerCount := len(workers)
+	workerCount, err := r.RunnerConfig.ResolveWorkers(r.loadStats().WorkerPeakMemory)
+	if err != nil {
+		return err
+	}
+
+	if rerunner, ok := r.Output.(output.Rerunner); ok {
+		rerunner.SetOnRerun(r.Rerun)
+	}
+	viewer, _ := r.Output.(timelineViewer)
+
+	recorder := r.newRecorder(tests)
+	if recorder != nil {
+		r.Output = recordingOutput{Output: r.Output, recorder: recorder}
+	}
+	timelineRecorder := r.newTimeline(viewer != nil)
+	if timelineRecorder != nil {
+		r.Output = timelineOutput{Output: r.Output, recorder: timelineRecorder}
+	}
+
+	if r.RunnerConfig.RandomOrder {
+		if r.RunnerConfig.Seed == 0 {
+			r.RunnerConfig.Seed = rand.Int64N(math.MaxInt32) + 1
+		}
+		tests = distributor.Shuffle(tests, r.RunnerConfig.Seed)
+	}
+
+	dist := distributor.RoundRobin(tests, min(workerCount, max(len(tests), 1)))
+	if r.RunnerConfig.RandomOrder {
+		dist.Shuffle(r.RunnerConfig.Seed)
+	}
+	workers := r.createWorkers(dist, hookTimeout)
+	workerCount = len(workers)
 	for _, w := range workers {
 		w.WorkerCount = workerCount
 	}
+	r.workers = workers
+	r.rerunIDs = rerunIDPool(workers)
 
 	if r.RunnerConfig.Before != "" {
 		cmd := exec.Command("sh", "-c", r.RunnerConfig.Before)
@@ -56,15 +115,24 @@ func (r *Runner) Run() error {
 		}
 	}
 
+	if r.RunnerConfig.Coverage.Enabled() {
+		if err := r.prepareCoverageDir(); err != nil {
+			return fmt.Errorf("failed to prepare coverage directory: %w", err)
+		}
+	}
+
+	stopSignals := r.handleSignals(shutdownGrace)
+	defer stopSignals()
+
 	var cleanupOnce sync.Once
 	cleanup := func() {
 		cleanupOnce.Do(func() {
 			if r.RunnerConfig.AfterWorker == "" {
 				return
 			}
-			// Ignore signals during cleanup so a second Ctrl+C doesn't kill the process
-			signal.Ignore(syscall.SIGINT, syscall.SIGTERM)
-			defer signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+			// Signals are ignored during cleanup so a second Ctrl+C doesn't skip it
+			r.cleaningUp.Store(true)
+			defer r.cleaningUp.Store(false)
 			total := len(workers)
 			var completed atomic.Int32
 			r.Output.CleanupProgress(0, total)
@@ -82,13 +150,22 @@ func (r *Runner) Run() error {
 		})
 	}
 
-	r.Output.SetOnCancel(cleanup)
+	runs := r.loadHistory()
+	r.Output.SetOnCancel(func() { r.Interrupt(shutdownGrace) })
 	r.Output.Start(output.StartOptions{
 		TestCount:    len(tests),
 		WorkerCount:  len(workers),
 		Filter:       r.RunnerConfig.Filter,
 		Group:        r.RunnerConfig.Group,
 		ExcludeGroup: r.RunnerConfig.ExcludeGroup,
+		PHPUnitArgs:  r.RunnerConfig.PHPUnitArgs,
+		Seed:         r.seed(),
+		KnownFlaky:   history.FlakyNames(runs),
+		Quarantine:   r.RunnerConfig.Quarantine,
+		Editor:       r.RunnerConfig.Editor,
+		BaseDir:      r.BaseDir,
+		WorkerFiles:  r.workerFiles(),
+		FileTimes:    history.FileTimes(runs),
 	})
 
 	var wg sync.WaitGroup
@@ -105,8 +182,46 @@ func (r *Runner) Run() error {
 	}
 
 	wg.Wait()
+	close(r.workersDone)
 	cleanup()
+	r.recordWorkerMemory(workers)
+
+	// A drained run only counts as stopped when it left files unrun, as the
+	// stop may have been requested while the last processes were finishing
+	stopped := r.cancelled.Load() || r.draining.Load() && filesLeft(workers) > 0
+	var coverageOutput []byte
+	var coverageErr error
+	if r.RunnerConfig.Coverage.Enabled() && !stopped {
+		coverageOutput, coverageErr = r.mergeCoverage()
+	}
+
+	var tl timeline.Timeline
+	if timelineRecorder != nil {
+		tl = timelineRecorder.Timeline()
+	}
+	if viewer != nil {
+		viewer.SetTimeline(tl)
+	}
+
 	r.Output.Finish()
+	r.stopReruns(shutdownGrace)
+
+	var timelineErr error
+	if r.RunnerConfig.Timeline != "" {
+		timelineErr = timeline.Write(r.RunnerConfig.Timeline, tl)
+	}
+
+	if len(coverageOutput) > 0 {
+		_, _ = os.Stdout.Write(coverageOutput)
+	}
+
+	if stopped {
+		return ErrCancelled
+	}
+
+	if recorder != nil {
+		r.saveHistory(recorder, workerCount)
+	}
 
 	if r.RunnerConfig.After != "" {
 		cmd := exec.Command("sh", "-c", r.RunnerConfig.After)
@@ -119,9 +234,49 @@ func (r *Runner) Run() error {
 		}
 	}
 
+	if coverageErr != nil {
+		return fmt.Errorf("failed to merge coverage: %w", coverageErr)
+	}
+
+	if timelineErr != nil {
+		return fmt.Errorf("failed to write timeline: %w", timelineErr)
+	}
+
 	return nil
 }
 
+func filesLeft(workers []*Worker) int {
+	left := 0
+	for _, w := range workers {
+		left += w.FilesLeft()
+	}
+	return left
+}
+
+// workerFiles returns the project-relative test files of each worker.
+func (r *Runner) workerFiles() map[int][]string {
+	files := make(map[int][]string, len(r.workers))
+	for _, w := range r.workers {
+		for _, t := range w.Tests {
+			rel, err := filepath.Rel(r.BaseDir, t.Path)
+			if err != nil {
+				rel = t.Path
+			}
+			files[w.ID] = append(files[w.ID], rel)
+		}
+	}
+	return files
+}
+
+// seed returns the random order seed, or zero when files run in their
+// discovered order.
+func (r *Runner) seed() int64 {
+	if !r.RunnerConfig.RandomOrder {
+		return 0
+	}
+	return r.RunnerConfig.Seed
+}
+
 func (r *Runner) env(workerCount int) []string {
 	return append(os.Environ(),
 		"PARALLEL=1",
@@ -131,27 +286,41 @@ func (r *Runner) env(workerCount int) []string {
 	)
 }
 
-func (r *Runner) createWorkers(dist distributor.Distribution) []*Worker {
+// workerConfig returns the settings of the run's workers. Coverage is only
+// collected by the workers of the run itself.
+func (r *Runner) workerConfig(hookTimeout time.Duration) WorkerConfig {
+	return WorkerConfig{
+		BeforeWorker:       r.RunnerConfig.BeforeWorker,
+		RunWorker:          r.RunnerConfig.RunWorker,
+		AfterWorker:        r.RunnerConfig.AfterWorker,
+		BaseDir:            r.BaseDir,
+		ConfigBuildDir:     r.RunnerConfig.ConfigBuildDir,
+		Bootstrap:          r.PHPUnitConfig.Bootstrap,
+		RawConfigXML:       r.PHPUnitConfig.RawXML,
+		Filter:             r.RunnerConfig.Filter,
+		Group:              r.RunnerConfig.Group,
+		ExcludeGroup:       r.RunnerConfig.ExcludeGroup,
+		PHPUnitArgs:        r.RunnerConfig.PHPUnitArgs,
+		RunWorkerArgs:      r.RunnerConfig.RunWorkerArgs,
+		Env:                r.RunnerConfig.Env,
+		HookTimeout:        hookTimeout,
+		MaxFilesPerProcess: r.RunnerConfig.MaxFilesPerProcess,
+	}
+}
+
+func (r *Runner) createWorkers(dist distributor.Distribution, hookTimeout time.Duration) []*Worker {
+	cfg := r.workerConfig(hookTimeout)
+	if r.RunnerConfig.Coverage.Enabled() {
+		cfg.CoverageDir = r.coverageDir()
+		cfg.CoverageDriver = r.RunnerConfig.Coverage.Driver
+	}
+
 	var workers []*Worker
 	for _, bucket := range dist.Workers {
 		if len(bucket.Tests) == 0 {
 			continue
 		}
-		workers = append(workers, NewWorker(
-			bucket.WorkerID,
-			bucket.Tests,
-			r.RunnerConfig.BeforeWorker,
-			r.RunnerConfig.RunWorker,
-			r.RunnerConfig.AfterWorker,
-			r.BaseDir,
-			r.RunnerConfig.ConfigBuildDir,
-			r.PHPUnitConfig.Bootstrap,
-			r.PHPUnitConfig.RawXML,
-			r.Output,
-			r.RunnerConfig.Filter,
-			r.RunnerConfig.Group,
-			r.RunnerConfig.ExcludeGroup,
-		))
+		workers = append(workers, NewWorker(bucket.WorkerID, bucket.Tests, cfg, r.Output))
 	}
 	return workers
 }
@@ -180,7 +349,7 @@ func (r *Runner) discoverTests() ([]distributor.TestFile, error) {
 		}
 	}
 
-	return tests, nil
+	return r.filterTests(tests), nil
 }
 
 func (r *Runner) findTestFiles(dir, suiteName string, excludes []string) ([]distributor.TestFile, error) {
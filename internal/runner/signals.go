@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// handleSignals interrupts the run on SIGINT and cancels it on SIGTERM,
+// independently of the output in use. Signals received while after-worker
+// hooks run are ignored so that a second Ctrl+C doesn't cut cleanup short. The
+// returned function stops listening.
+func (r *Runner) handleSignals(grace time.Duration) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if r.cleaningUp.Load() {
+					continue
+				}
+				if sig == syscall.SIGINT {
+					r.Interrupt(grace)
+				} else {
+					r.Cancel(grace)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Interrupt drains the run the first time it is called, letting running
+// PHPUnit processes finish without starting new ones, and cancels it on any
+// subsequent call.
+func (r *Runner) Interrupt(grace time.Duration) {
+	if !r.draining.CompareAndSwap(false, true) {
+		r.Cancel(grace)
+		return
+	}
+
+	r.Output.Draining()
+	for _, w := range r.workers {
+		w.Drain()
+	}
+}
+
+// Cancel stops the run by sending SIGTERM to every worker's process group,
+// escalating to SIGKILL for any still running once the grace period expires.
+func (r *Runner) Cancel(grace time.Duration) {
+	r.cancelOnce.Do(func() {
+		r.cancelled.Store(true)
+		r.Output.Cancel()
+
+		for _, w := range r.workers {
+			w.Signal(syscall.SIGTERM)
+		}
+
+		go func() {
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+
+			select {
+			case <-r.workersDone:
+			case <-timer.C:
+				for _, w := range r.workers {
+					w.Signal(syscall.SIGKILL)
+				}
+			}
+		}()
+	})
+}
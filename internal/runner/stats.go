@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// runStats holds measurements carried over between runs.
+type runStats struct {
+	WorkerPeakMemory uint64 `json:"worker_peak_memory"`
+}
+
+func (r *Runner) statsPath() string {
+	return filepath.Join(r.RunnerConfig.ConfigBuildDir, "stats.json")
+}
+
+func (r *Runner) loadStats() runStats {
+	var stats runStats
+	data, err := os.ReadFile(r.statsPath())
+	if err != nil {
+		return stats
+	}
+	_ = json.Unmarshal(data, &stats)
+	return stats
+}
+
+func (r *Runner) saveStats(stats runStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.RunnerConfig.ConfigBuildDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.statsPath(), data, 0644)
+}
+
+// recordWorkerMemory remembers the highest peak memory of any worker so that
+// future runs can size the worker count to the available memory.
+func (r *Runner) recordWorkerMemory(workers []*Worker) {
+	var peak uint64
+	for _, w := range workers {
+		if w.usage != nil {
+			peak = max(peak, w.usage.current().PeakRSS)
+		}
+	}
+	if peak == 0 {
+		return
+	}
+
+	stats := r.loadStats()
+	stats.WorkerPeakMemory = peak
+	_ = r.saveStats(stats)
+}
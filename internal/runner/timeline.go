@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"path/filepath"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+	"github.com/alexdempster44/phpunit-parallel/internal/timeline"
+)
+
+// timelineOutput passes worker events on to the timeline recorder as well as
+// the configured output.
+type timelineOutput struct {
+	output.Output
+	recorder *timeline.Recorder
+}
+
+func (o timelineOutput) WorkerStart(workerID, testCount int) {
+	o.recorder.WorkerStart(workerID)
+	o.Output.WorkerStart(workerID, testCount)
+}
+
+func (o timelineOutput) WorkerHook(workerID int, result output.HookResult) {
+	o.recorder.Hook(workerID, result)
+	o.Output.WorkerHook(workerID, result)
+}
+
+func (o timelineOutput) WorkerLine(workerID int, line string) {
+	o.recorder.Line(workerID, line)
+	o.Output.WorkerLine(workerID, line)
+}
+
+func (o timelineOutput) WorkerComplete(workerID int, err error) {
+	o.recorder.WorkerComplete(workerID)
+	o.Output.WorkerComplete(workerID, err)
+}
+
+// timelineViewer is implemented by outputs that show the timeline once the run
+// has finished.
+type timelineViewer interface {
+	SetTimeline(tl timeline.Timeline)
+}
+
+// newTimeline returns a recorder when the timeline is shown by the output or
+// written to a file, and nil otherwise.
+func (r *Runner) newTimeline(shown bool) *timeline.Recorder {
+	if !shown && r.RunnerConfig.Timeline == "" {
+		return nil
+	}
+	baseDir, err := filepath.Abs(r.BaseDir)
+	if err != nil {
+		baseDir = r.BaseDir
+	}
+	return timeline.NewRecorder(baseDir)
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+const usageSampleInterval = 500 * time.Millisecond
+
+// usageFileQueue is how many file switches the worker's output can get ahead of
+// the usage monitor before it waits.
+const usageFileQueue = 64
+
+var errUsageUnsupported = errors.New("resource usage sampling is not supported on this platform")
+
+// usageMonitor samples the memory and CPU time of a worker's PHPUnit process
+// group, attributing peak memory to the test file running at the time.
+type usageMonitor struct {
+	mu      sync.Mutex
+	baseCPU time.Duration
+	usage   output.ResourceUsage
+}
+
+func newUsageMonitor() *usageMonitor {
+	return &usageMonitor{}
+}
+
+// setFile moves on to the next file, whose peak memory starts from zero.
+func (u *usageMonitor) setFile(file string) output.ResourceUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.usage.CurrentFile = file
+	u.usage.FilePeak = 0
+	return u.usage
+}
+
+func (u *usageMonitor) current() output.ResourceUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usage
+}
+
+// sample records the usage of the process group led by pgid.
+func (u *usageMonitor) sample(pgid int) (output.ResourceUsage, error) {
+	rss, cpu, err := sampleProcessGroup(pgid)
+	if err != nil {
+		return output.ResourceUsage{}, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.usage.RSS = rss
+	u.usage.CPUTime = u.baseCPU + cpu
+	u.usage.PeakRSS = max(u.usage.PeakRSS, rss)
+	if u.usage.CurrentFile != "" {
+		u.usage.FilePeak = max(u.usage.FilePeak, rss)
+	}
+	return u.usage, nil
+}
+
+// run samples the process group led by pgid until files is closed. Each file
+// received on files becomes the current one after a final sample of the file
+// that was running, so that short files are still attributed without holding
+// up the worker's output. CPU time accumulates across batches as each batch is
+// a new process group.
+func (u *usageMonitor) run(pgid int, files <-chan string, report func(output.ResourceUsage)) {
+	ticker := time.NewTicker(usageSampleInterval)
+	defer ticker.Stop()
+
+	u.mu.Lock()
+	u.baseCPU = u.usage.CPUTime
+	u.mu.Unlock()
+
+	for {
+		select {
+		case file, ok := <-files:
+			if !ok {
+				return
+			}
+			if usage, err := u.sample(pgid); err == nil {
+				report(usage)
+			}
+			report(u.setFile(file))
+		case <-ticker.C:
+			if usage, err := u.sample(pgid); err == nil {
+				report(usage)
+			}
+		}
+	}
+}
+
+// testFileFromLine returns the test file of a class-level testSuiteStarted
+// line, whose locationHint has the form php_qn://<file>::\<class>.
+func (w *Worker) testFileFromLine(line string) string {
+	if !strings.HasPrefix(line, "##teamcity[testSuiteStarted ") {
+		return ""
+	}
+
+	hint := output.ParseTeamCityAttr(line, "locationHint")
+	path, ok := strings.CutPrefix(hint, "php_qn://")
+	if !ok || strings.Count(path, "::") != 1 {
+		return ""
+	}
+	path, _, _ = strings.Cut(path, "::")
+
+	path = filepath.Clean(path)
+	if rel, err := filepath.Rel(w.BaseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
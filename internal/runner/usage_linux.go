@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on all mainstream Linux platforms.
+const clockTicks = 100
+
+var errMalformedStat = errors.New("malformed /proc stat")
+
+// sampleProcessGroup sums the resident memory and CPU time of the process group
+// led by pgid. Rather than scanning all of /proc, it follows the leader's
+// descendants through /proc/<pid>/task/<tid>/children, skipping any that moved
+// to another group.
+func sampleProcessGroup(pgid int) (uint64, time.Duration, error) {
+	pageSize := uint64(os.Getpagesize())
+	var rss uint64
+	var ticks uint64
+	pending := []int{pgid}
+	for len(pending) > 0 {
+		pid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		fields, err := readStat(pid)
+		if err != nil {
+			if pid == pgid {
+				return 0, 0, err
+			}
+			continue
+		}
+		if group, _ := strconv.Atoi(fields[2]); group != pgid {
+			continue
+		}
+
+		utime, _ := strconv.ParseUint(fields[11], 10, 64)
+		stime, _ := strconv.ParseUint(fields[12], 10, 64)
+		pages, _ := strconv.ParseUint(fields[21], 10, 64)
+		ticks += utime + stime
+		rss += pages * pageSize
+
+		pending = append(pending, childPIDs(pid)...)
+	}
+
+	return rss, time.Duration(ticks) * time.Second / clockTicks, nil
+}
+
+// readStat returns the fields of /proc/<pid>/stat that follow the command name,
+// which may itself contain spaces, so that field 3 (state) of proc(5) comes
+// first.
+func readStat(pid int) ([]string, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return nil, err
+	}
+
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return nil, errMalformedStat
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return nil, errMalformedStat
+	}
+	return fields, nil
+}
+
+// childPIDs returns the children of every thread of pid.
+func childPIDs(pid int) []int {
+	taskDir := "/proc/" + strconv.Itoa(pid) + "/task/"
+	tasks, err := os.ReadDir(taskDir)
+	if err != nil {
+		return nil
+	}
+
+	var children []int
+	for _, task := range tasks {
+		data, err := os.ReadFile(taskDir + task.Name() + "/children")
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(data)) {
+			if child, err := strconv.Atoi(field); err == nil {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
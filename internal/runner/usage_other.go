@@ -0,0 +1,9 @@
+//go:build !linux
+
+package runner
+
+import "time"
+
+func sampleProcessGroup(pgid int) (uint64, time.Duration, error) {
+	return 0, 0, errUsageUnsupported
+}
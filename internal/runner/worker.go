@@ -3,63 +3,145 @@ package runner
 import (
 	"bufio"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/alexdempster44/phpunit-parallel/internal/config"
 	"github.com/alexdempster44/phpunit-parallel/internal/distributor"
 	"github.com/alexdempster44/phpunit-parallel/internal/output"
 )
 
+var errWorkerStopped = errors.New("worker stopped")
+
+// WorkerConfig holds the settings every worker of a run shares.
+type WorkerConfig struct {
+	BeforeWorker       string
+	RunWorker          string
+	AfterWorker        string
+	BaseDir            string
+	ConfigBuildDir     string
+	Bootstrap          string
+	RawConfigXML       []byte
+	Filter             string
+	Group              string
+	ExcludeGroup       string
+	PHPUnitArgs        []string
+	RunWorkerArgs      []string
+	Env                []config.EnvVar
+	HookTimeout        time.Duration
+	MaxFilesPerProcess int
+	CoverageDir        string
+	CoverageDriver     string
+	// LogName is the file in ConfigBuildDir that hook output is written to,
+	// worker-<id>.log by default.
+	LogName string
+}
+
 type Worker struct {
-	ID             int
-	Tests          []distributor.TestFile
-	BeforeWorker   string
-	RunWorker      string
-	AfterWorker    string
-	BaseDir        string
-	ConfigBuildDir string
-	Bootstrap      string
-	RawConfigXML   []byte
-	Output         output.Output
-	Filter         string
-	Group          string
-	ExcludeGroup   string
-	WorkerCount    int
-}
-
-func NewWorker(id int, tests []distributor.TestFile, beforeWorker, runWorker, afterWorker, baseDir, configBuildDir, bootstrap string, rawConfigXML []byte, out output.Output, filter, group, excludeGroup string) *Worker {
+	WorkerConfig
+	ID          int
+	Tests       []distributor.TestFile
+	Output      output.Output
+	WorkerCount int
+	expandedEnv []string
+	usage       *usageMonitor
+
+	mu       sync.Mutex
+	process  *os.Process
+	stopped  bool
+	draining bool
+	// filesLeft counts the files not run because the worker was drained.
+	filesLeft int
+}
+
+func NewWorker(id int, tests []distributor.TestFile, cfg WorkerConfig, out output.Output) *Worker {
 	return &Worker{
-		ID:             id,
-		Tests:          tests,
-		BeforeWorker:   beforeWorker,
-		RunWorker:      runWorker,
-		AfterWorker:    afterWorker,
-		BaseDir:        baseDir,
-		ConfigBuildDir: configBuildDir,
-		Bootstrap:      bootstrap,
-		RawConfigXML:   rawConfigXML,
-		Output:         out,
-		Filter:         filter,
-		Group:          group,
-		ExcludeGroup:   excludeGroup,
+		WorkerConfig: cfg,
+		ID:           id,
+		Tests:        tests,
+		Output:       out,
 	}
 }
 
 func (w *Worker) Run() error {
+	expandedEnv, err := w.expandEnv()
+	if err != nil {
+		return fmt.Errorf("failed to expand env: %w", err)
+	}
+	w.expandedEnv = expandedEnv
+	w.usage = newUsageMonitor()
+	_ = os.Remove(w.logPath())
+
 	if w.BeforeWorker != "" {
-		if err := w.runHook(w.BeforeWorker); err != nil {
+		if err := w.runHook("before-worker", w.BeforeWorker, true); err != nil {
 			return fmt.Errorf("before-worker failed: %w", err)
 		}
 	}
 
-	configPath, err := w.buildConfig()
+	var firstErr error
+	ran := 0
+	for i, batch := range w.batches() {
+		if w.isDraining() {
+			break
+		}
+
+		w.Output.WorkerBatch(w.ID, len(batch))
+		n, err := w.runBatch(i, batch)
+		ran += n
+		if errors.Is(err, errWorkerStopped) {
+			break
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	w.mu.Lock()
+	w.filesLeft = len(w.Tests) - ran
+	w.mu.Unlock()
+
+	return firstErr
+}
+
+// FilesLeft returns the number of the worker's files that didn't run
+// because the run was stopped.
+func (w *Worker) FilesLeft() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.filesLeft
+}
+
+// batches splits the worker's tests into groups run by separate PHPUnit
+// processes, which releases memory leaked by long-lived processes and allows
+// the worker to stop between them when draining. Hooks still run once per
+// worker rather than per batch.
+func (w *Worker) batches() [][]distributor.TestFile {
+	size := w.MaxFilesPerProcess
+	if size <= 0 || size >= len(w.Tests) {
+		return [][]distributor.TestFile{w.Tests}
+	}
+
+	var batches [][]distributor.TestFile
+	for i := 0; i < len(w.Tests); i += size {
+		batches = append(batches, w.Tests[i:min(i+size, len(w.Tests))])
+	}
+	return batches
+}
+
+// runBatch runs tests in a single PHPUnit process and returns how many of them
+// ran, which is fewer than given when the worker was drained part way through.
+func (w *Worker) runBatch(index int, tests []distributor.TestFile) (int, error) {
+	configPath, err := w.buildConfig(tests)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %w", err)
+		return len(tests), fmt.Errorf("failed to build config: %w", err)
 	}
 	defer func() { _ = os.Remove(configPath) }()
 
@@ -73,70 +155,197 @@ func (w *Worker) Run() error {
 	if w.ExcludeGroup != "" {
 		args = append(args, "--exclude-group", w.ExcludeGroup)
 	}
-	joinedArgs := strings.Join(args, " ")
-	var cmd *exec.Cmd
-	if strings.Contains(w.RunWorker, "{}") {
-		shellArgs := strings.ReplaceAll(w.RunWorker, "{}", joinedArgs)
-		cmd = exec.Command("sh", "-c", shellArgs)
-	} else if strings.Contains(w.RunWorker, " ") {
-		cmd = exec.Command("sh", "-c", w.RunWorker+" "+joinedArgs)
-	} else {
-		cmd = exec.Command(w.RunWorker, args...)
+	if w.CoverageDir != "" {
+		args = append(args, "--coverage-php", filepath.Join(w.CoverageDir, fmt.Sprintf("worker-%d-%d.cov", w.ID, index)))
 	}
+	args = append(args, w.PHPUnitArgs...)
+	cmd := w.command(configPath, tests, args)
 	cmd.Dir = w.BaseDir
-	cmd.Env = w.env()
+	cmd.Env = append(w.env(), w.coverageEnv()...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+		return len(tests), fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
+	if err := w.start(cmd); err != nil {
+		if errors.Is(err, errWorkerStopped) {
+			return 0, err
+		}
+		return len(tests), fmt.Errorf("failed to start command: %w", err)
 	}
+	defer w.release()
+
+	w.Output.WorkerProcess(w.ID, output.ProcessInfo{
+		PID:        cmd.Process.Pid,
+		Command:    output.ShellJoin(cmd.Args),
+		ConfigPath: configPath,
+	})
 
+	pgid := cmd.Process.Pid
+	report := func(usage output.ResourceUsage) { w.Output.WorkerUsage(w.ID, usage) }
+	files := make(chan string, usageFileQueue)
+	sampled := make(chan struct{})
+	go func() {
+		w.usage.run(pgid, files, report)
+		close(sampled)
+	}()
+
+	// running holds the names of the file-level suites that have started but
+	// not finished, as testSuiteFinished carries no location
+	running := make(map[string]bool)
+	ran := 0
+	drained := false
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
-		w.Output.WorkerLine(w.ID, scanner.Text())
+		line := scanner.Text()
+		if file := w.testFileFromLine(line); file != "" {
+			running[output.ParseTeamCityAttr(line, "name")] = true
+			files <- file
+		}
+		w.Output.WorkerLine(w.ID, line)
+
+		if strings.HasPrefix(line, "##teamcity[testSuiteFinished ") {
+			name := output.ParseTeamCityAttr(line, "name")
+			if running[name] {
+				delete(running, name)
+				ran++
+				if ran < len(tests) && w.stopDrained() {
+					drained = true
+				}
+			}
+		}
+	}
+
+	close(files)
+	err = cmd.Wait()
+	<-sampled
+	usage := w.usage.current()
+	report(w.usage.setFile(""))
+
+	if drained {
+		return ran, errWorkerStopped
+	}
+	if err != nil {
+		if killed(err) && usage.CurrentFile != "" {
+			return len(tests), fmt.Errorf("command failed while running %s (%s resident): %w", usage.CurrentFile, output.FormatBytes(usage.RSS), err)
+		}
+		return len(tests), fmt.Errorf("command failed: %w", err)
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("command failed: %w", err)
+	return len(tests), nil
+}
+
+// killed reports whether the process was killed by a signal, either directly
+// or as seen through a wrapping shell.
+func killed(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return true
 	}
+	return exitErr.ExitCode() > 128
+}
+
+// start launches cmd as the worker's current process so that Signal can reach
+// it. Once the worker has been signalled no new processes are started.
+func (w *Worker) start(cmd *exec.Cmd) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
+	if w.stopped {
+		return errWorkerStopped
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	w.process = cmd.Process
 	return nil
 }
 
-func (w *Worker) runHook(command string) error {
-	cmd := exec.Command("sh", "-c", command)
-	cmd.Dir = w.BaseDir
-	cmd.Env = w.env()
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	return cmd.Run()
+func (w *Worker) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.process = nil
+}
+
+// Drain lets the worker's current test file finish but prevents any further
+// files from starting.
+func (w *Worker) Drain() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.draining = true
+}
+
+// stopDrained terminates the process group of the worker's current process if
+// the worker is draining, and reports whether it did.
+func (w *Worker) stopDrained() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.draining || w.process == nil {
+		return false
+	}
+	_ = syscall.Kill(-w.process.Pid, syscall.SIGTERM)
+	return true
+}
+
+func (w *Worker) isDraining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.draining || w.stopped
+}
+
+// Signal sends sig to the process group of the worker's current process and
+// prevents any further processes from being started.
+func (w *Worker) Signal(sig syscall.Signal) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	if w.process != nil {
+		_ = syscall.Kill(-w.process.Pid, sig)
+	}
 }
 
 func (w *Worker) env() []string {
-	return append(os.Environ(),
+	env := append(os.Environ(),
 		"PARALLEL=1",
 		fmt.Sprintf("PROJECT=%s", filepath.Base(w.BaseDir)),
 		fmt.Sprintf("RUNNER_PID=%d", os.Getpid()),
 		fmt.Sprintf("WORKER_ID=%d", w.ID),
 		fmt.Sprintf("WORKER_COUNT=%d", w.WorkerCount),
 	)
+	return append(env, w.expandedEnv...)
+}
+
+// coverageEnv enables the selected coverage driver for PHPUnit. PCOV is
+// enabled by default once installed, so selecting it only disables Xdebug.
+func (w *Worker) coverageEnv() []string {
+	switch w.CoverageDriver {
+	case "xdebug":
+		return []string{"XDEBUG_MODE=coverage"}
+	case "pcov":
+		return []string{"XDEBUG_MODE=off"}
+	}
+	return nil
 }
 
 func (w *Worker) runAfterWorker() {
 	if w.AfterWorker == "" {
 		return
 	}
-	_ = w.runHook(w.AfterWorker)
+	_ = w.runHook("after-worker", w.AfterWorker, false)
 }
 
 func (w *Worker) TestCount() int {
 	return len(w.Tests)
 }
 
-func (w *Worker) buildConfig() (string, error) {
+func (w *Worker) buildConfig(tests []distributor.TestFile) (string, error) {
 	type testFile struct {
 		XMLName xml.Name `xml:"file"`
 		Path    string   `xml:",chardata"`
@@ -153,16 +362,18 @@ func (w *Worker) buildConfig() (string, error) {
 		TestSuites []testSuite `xml:"testsuite"`
 	}
 
-	suiteMap := make(map[string][]testFile)
-	for _, test := range w.Tests {
+	// Each run of consecutive files from the same suite gets its own block, so
+	// a suite can appear more than once but PHPUnit runs the files in the
+	// order given.
+	var suites []testSuite
+	for _, test := range tests {
 		relPath, _ := filepath.Rel(w.BaseDir, test.Path)
 		pathFromConfig := filepath.Join("..", relPath)
-		suiteMap[test.Suite] = append(suiteMap[test.Suite], testFile{Path: pathFromConfig})
-	}
-
-	var suites []testSuite
-	for name, files := range suiteMap {
-		suites = append(suites, testSuite{Name: name, Files: files})
+		if len(suites) == 0 || suites[len(suites)-1].Name != test.Suite {
+			suites = append(suites, testSuite{Name: test.Suite})
+		}
+		last := &suites[len(suites)-1]
+		last.Files = append(last.Files, testFile{Path: pathFromConfig})
 	}
 
 	newTestSuites := testSuites{TestSuites: suites}
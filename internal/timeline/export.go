@@ -0,0 +1,168 @@
+package timeline
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	svgWidth    = 1200
+	labelWidth  = 90
+	rowHeight   = 28
+	barHeight   = 20
+	axisHeight  = 30
+	svgFontSize = 12
+)
+
+var fileColors = [2]string{"#4c9be8", "#2f76c0"}
+
+const (
+	hookColor   = "#e8b54c"
+	failedColor = "#e0564f"
+)
+
+// Write exports the timeline to path, as an SVG image when the path ends in
+// .svg and as an HTML page otherwise.
+func Write(path string, t Timeline) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		err = WriteSVG(w, t)
+	} else {
+		err = WriteHTML(w, t)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteSVG draws one row per worker with a bar for each hook and test file.
+// Gaps between bars are idle time. Hovering a bar shows its label and
+// duration.
+func WriteSVG(w io.Writer, t Timeline) error {
+	height := axisHeight + len(t.Workers)*rowHeight + 10
+	chartWidth := float64(svgWidth - labelWidth - 20)
+	total := max(t.Duration, time.Millisecond)
+	x := func(d time.Duration) float64 {
+		return labelWidth + chartWidth*float64(d)/float64(total)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="%d">`+"\n", svgWidth, height, svgFontSize)
+
+	for _, tick := range ticks(total) {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", x(tick), axisHeight-8, x(tick), height-10)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#666">%s</text>`+"\n", x(tick), axisHeight-12, formatDuration(tick))
+	}
+
+	for i, worker := range t.Workers {
+		y := axisHeight + i*rowHeight
+		idle := 0.0
+		if span := worker.End - worker.Start; span > 0 {
+			idle = float64(worker.Idle()) * 100 / float64(span)
+		}
+		fmt.Fprintf(&b, `<text x="0" y="%d">Worker %d</text>`+"\n", y+barHeight-5, worker.ID+1)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#f2f2f2"><title>Worker %d: %s, %.0f%% idle</title></rect>`+"\n",
+			x(worker.Start), y, max(x(worker.End)-x(worker.Start), 0), barHeight, worker.ID+1, formatDuration(worker.End-worker.Start), idle)
+
+		files := 0
+		for _, s := range worker.Spans {
+			color := hookColor
+			if s.Kind == KindFile {
+				color = fileColors[files%2]
+				files++
+			}
+			if s.Failed {
+				color = failedColor
+			}
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.2f" height="%d" fill="%s"><title>%s (%s)</title></rect>`+"\n",
+				x(s.Start), y, max(x(s.End)-x(s.Start), 0.5), barHeight, color, html.EscapeString(s.Label), formatDuration(s.Duration()))
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHTML writes a standalone page with the SVG timeline and a summary of
+// each worker's busy and idle time.
+func WriteHTML(w io.Writer, t Timeline) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>phpunit-parallel timeline</title>\n")
+	b.WriteString("<style>body{font-family:sans-serif;margin:20px}table{border-collapse:collapse;margin-top:20px}td,th{padding:4px 12px;text-align:right}th:first-child,td:first-child{text-align:left}</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>Run timeline</h1>\n<p>Started %s, took %s. Hover a bar for details.</p>\n", t.Started.Format(time.RFC1123), formatDuration(t.Duration))
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	if err := WriteSVG(w, t); err != nil {
+		return err
+	}
+
+	b.Reset()
+	b.WriteString("<table>\n<tr><th>Worker</th><th>Files</th><th>Hooks</th><th>Tests</th><th>Idle</th><th>Total</th></tr>\n")
+	for _, worker := range t.Workers {
+		var files int
+		var hooks, tests time.Duration
+		for _, s := range worker.Spans {
+			if s.Kind == KindHook {
+				hooks += s.Duration()
+			} else {
+				files++
+				tests += s.Duration()
+			}
+		}
+		fmt.Fprintf(&b, "<tr><td>Worker %d</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			worker.ID+1, files, formatDuration(hooks), formatDuration(tests), formatDuration(worker.Idle()), formatDuration(worker.End-worker.Start))
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ticks returns around ten evenly spaced round offsets across total.
+func ticks(total time.Duration) []time.Duration {
+	step := time.Millisecond
+	for _, s := range []time.Duration{
+		10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 500 * time.Millisecond,
+		time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+		time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour,
+	} {
+		if total/s < 10 {
+			break
+		}
+		step = s
+	}
+
+	var ticks []time.Duration
+	for d := time.Duration(0); d <= total; d += step {
+		ticks = append(ticks, d)
+	}
+	return ticks
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,201 @@
+package timeline
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexdempster44/phpunit-parallel/internal/output"
+)
+
+type Kind int
+
+const (
+	KindHook Kind = iota
+	KindFile
+)
+
+// Span is a period of a worker's time, as offsets from the start of the run.
+type Span struct {
+	Kind   Kind
+	Label  string
+	Start  time.Duration
+	End    time.Duration
+	Failed bool
+}
+
+func (s Span) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+type Worker struct {
+	ID    int
+	Start time.Duration
+	End   time.Duration
+	Spans []Span
+}
+
+// Busy returns the time the worker spent in hooks and test files.
+func (w Worker) Busy() time.Duration {
+	var busy time.Duration
+	for _, s := range w.Spans {
+		busy += s.Duration()
+	}
+	return busy
+}
+
+// Idle returns the time between the worker starting and finishing that wasn't
+// spent in a hook or test file, such as PHPUnit booting.
+func (w Worker) Idle() time.Duration {
+	return max(w.End-w.Start-w.Busy(), 0)
+}
+
+// At returns the span running at the given offset.
+func (w Worker) At(offset time.Duration) (Span, bool) {
+	for _, s := range w.Spans {
+		if offset >= s.Start && offset < s.End {
+			return s, true
+		}
+	}
+	return Span{}, false
+}
+
+type Timeline struct {
+	Started  time.Time
+	Duration time.Duration
+	Workers  []Worker
+}
+
+// Recorder builds a Timeline from worker events as they happen.
+type Recorder struct {
+	mu      sync.Mutex
+	baseDir string
+	started time.Time
+	workers map[int]*Worker
+	open    map[int]map[string]int
+}
+
+// NewRecorder starts recording a run. File paths are shown relative to
+// baseDir.
+func NewRecorder(baseDir string) *Recorder {
+	return &Recorder{
+		baseDir: baseDir,
+		started: time.Now(),
+		workers: make(map[int]*Worker),
+		open:    make(map[int]map[string]int),
+	}
+}
+
+func (r *Recorder) worker(id int) *Worker {
+	w := r.workers[id]
+	if w == nil {
+		now := time.Since(r.started)
+		w = &Worker{ID: id, Start: now, End: now}
+		r.workers[id] = w
+		r.open[id] = make(map[string]int)
+	}
+	return w
+}
+
+func (r *Recorder) WorkerStart(workerID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.worker(workerID)
+}
+
+// Hook records a hook once it has finished.
+func (r *Recorder) Hook(workerID int, result output.HookResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := r.worker(workerID)
+	end := time.Since(r.started)
+	w.Spans = append(w.Spans, Span{
+		Kind:   KindHook,
+		Label:  result.Hook,
+		Start:  max(end-result.Duration, w.Start),
+		End:    end,
+		Failed: result.Err != nil,
+	})
+	w.End = end
+}
+
+// Line records the test files a worker runs from its TeamCity output. Files
+// are the suites whose location has no method.
+func (r *Recorder) Line(workerID int, line string) {
+	started := strings.HasPrefix(line, "##teamcity[testSuiteStarted ")
+	finished := strings.HasPrefix(line, "##teamcity[testSuiteFinished ")
+	failed := strings.HasPrefix(line, "##teamcity[testFailed ")
+	if !started && !finished && !failed {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := r.worker(workerID)
+	now := time.Since(r.started)
+	open := r.open[workerID]
+	name := output.ParseTeamCityAttr(line, "name")
+
+	switch {
+	case started:
+		hint, ok := strings.CutPrefix(output.ParseTeamCityAttr(line, "locationHint"), "php_qn://")
+		if !ok || strings.Count(hint, "::") != 1 {
+			return
+		}
+		file, _, _ := strings.Cut(hint, "::")
+		if rel, err := filepath.Rel(r.baseDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		open[name] = len(w.Spans)
+		w.Spans = append(w.Spans, Span{Kind: KindFile, Label: file, Start: now, End: now})
+
+	case finished:
+		if i, ok := open[name]; ok {
+			w.Spans[i].End = now
+			delete(open, name)
+		}
+		w.End = now
+
+	case failed:
+		for _, i := range open {
+			w.Spans[i].Failed = true
+		}
+	}
+}
+
+func (r *Recorder) WorkerComplete(workerID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := r.worker(workerID)
+	now := time.Since(r.started)
+	for name, i := range r.open[workerID] {
+		w.Spans[i].End = now
+		delete(r.open[workerID], name)
+	}
+	w.End = now
+}
+
+func (r *Recorder) Timeline() Timeline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := Timeline{Started: r.started}
+	for _, w := range r.workers {
+		worker := *w
+		worker.Spans = append([]Span(nil), w.Spans...)
+		sort.SliceStable(worker.Spans, func(i, j int) bool {
+			return worker.Spans[i].Start < worker.Spans[j].Start
+		})
+		t.Workers = append(t.Workers, worker)
+		t.Duration = max(t.Duration, w.End)
+	}
+	sort.Slice(t.Workers, func(i, j int) bool {
+		return t.Workers[i].ID < t.Workers[j].ID
+	})
+	return t
+}